@@ -0,0 +1,83 @@
+//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
+package executor
+
+import (
+	"context"
+	"fmt"
+	"github.com/akaumov/go-service/exchange"
+	"sync/atomic"
+)
+
+// Client calls the methods of the service through a transport, e.g.
+// httptransport.NewClient, wstransport.Dial or exchange.LocalTransport.
+type Client struct {
+	transport exchange.Transport
+	lastId    uint64
+}
+
+func NewClient(transport exchange.Transport) *Client {
+	return &Client{
+		transport: transport,
+	}
+}
+
+func (c *Client) call(ctx context.Context, method string, params Validatable, result interface{}) error {
+	err := params.Validate()
+	if err != nil {
+		return exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %v", err), err)
+	}
+
+	id := atomic.AddUint64(&c.lastId, 1)
+
+	err = exchange.Call(ctx, c.transport, id, method, params, result)
+	if callErr, ok := err.(*exchange.Error); ok {
+		return decodeError(callErr)
+	}
+
+	return err
+}
+
+// decodeError returns declared errors as their types.
+func decodeError(err *exchange.Error) error {
+	return err
+}
+
+func (c *Client) GetBook(ctx context.Context, id string) (*Book, error) {
+	params := GetBookParams{
+		Id: id,
+	}
+
+	var result *Book
+	err := c.call(ctx, "getBook", &params, &result)
+	return result, err
+}
+
+func (c *Client) GetBooks(ctx context.Context, id string) (*[]Book, error) {
+	params := GetBooksParams{
+		Id: id,
+	}
+
+	var result *[]Book
+	err := c.call(ctx, "getBooks", &params, &result)
+	return result, err
+}
+
+func (c *Client) GetAuthor(ctx context.Context, id string) (*Author, error) {
+	params := GetAuthorParams{
+		Id: id,
+	}
+
+	var result *Author
+	err := c.call(ctx, "getAuthor", &params, &result)
+	return result, err
+}
+
+func (c *Client) GetAuthors(ctx context.Context, id string) (*[]Author, error) {
+	params := GetAuthorsParams{
+		Id: id,
+	}
+
+	var result *[]Author
+	err := c.call(ctx, "getAuthors", &params, &result)
+	return result, err
+}
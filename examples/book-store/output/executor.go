@@ -4,125 +4,223 @@ package executor
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/pkg/errors"
-
 	"github.com/akaumov/go-service/exchange"
+	"github.com/pkg/errors"
+	"log"
 )
 
 type Executor struct {
-	handler HandlerInterface
+	handler          HandlerInterface
+	jsonRpc          bool
+	batchConcurrency int
+	interceptors     []Interceptor
+	reportError      ErrorReporter
 }
 
-type SessionInterface interface {
-	GetUserId() string
-	GetSessionId() string
+type SessionInterface = exchange.Session
+
+type ExecutorOption func(e *Executor)
+
+// WithJsonRpc makes the executor speak JSON-RPC 2.0 instead of the legacy envelope.
+func WithJsonRpc() ExecutorOption {
+	return func(e *Executor) {
+		e.jsonRpc = true
+	}
 }
 
-func NewExecutor(handler HandlerInterface) *Executor {
-	return &Executor{
-		handler: handler,
+// WithBatchConcurrency runs up to limit requests of a batch concurrently.
+// Batches are run sequentially by default.
+func WithBatchConcurrency(limit int) ExecutorOption {
+	return func(e *Executor) {
+		e.batchConcurrency = limit
 	}
 }
 
+// Call is a handler call seen by interceptors. Params holds the decoded and
+// validated params of the method, e.g. *GetBookParams.
+type Call struct {
+	Method  string
+	Params  interface{}
+	Session SessionInterface
+}
+
+type CallHandler func(call *Call) (interface{}, error)
+
+// Interceptor wraps handler calls. It may inspect or change the call, return
+// without calling next or rewrite the result and the error of next.
+type Interceptor func(call *Call, next CallHandler) (interface{}, error)
+
+// WithInterceptors adds interceptors to the executor. The first interceptor
+// is the outermost one: it is called first and gets the result last.
+func WithInterceptors(interceptors ...Interceptor) ExecutorOption {
+	return func(e *Executor) {
+		e.interceptors = append(e.interceptors, interceptors...)
+	}
+}
+
+// ErrorReporter gets the errors that are hidden from clients: errors of handlers
+// and interceptors that aren't *exchange.Error and recovered panics
+// (*exchange.PanicError). correlationId is sent to the client instead.
+type ErrorReporter func(call *Call, correlationId string, err error)
+
+// WithErrorReporter replaces the default reporter, which logs through the log package.
+func WithErrorReporter(reporter ErrorReporter) ExecutorOption {
+	return func(e *Executor) {
+		e.reportError = reporter
+	}
+}
+
+func logError(call *Call, correlationId string, err error) {
+	log.Printf("%v failed, correlation id %v: %v", call.Method, correlationId, err)
+}
+
+func NewExecutor(handler HandlerInterface, options ...ExecutorOption) *Executor {
+	e := &Executor{
+		handler:     handler,
+		reportError: logError,
+	}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return e
+}
+
+// Execute runs a request or a batch of requests. It returns nil response if
+// there is nothing to respond, i.e. for JSON-RPC 2.0 notifications.
 func (e *Executor) Execute(session SessionInterface, packedMessage *[]byte) (*[]byte, error) {
 	if packedMessage == nil {
 		return nil, errors.New("message text is required")
 	}
 
-	response := e.execute(session, packedMessage)
-	packed, _ := json.Marshal(response)
-	return &packed, nil
-}
+	call := func(method string, params json.RawMessage) (interface{}, *exchange.Error) {
+		return e.call(session, method, params)
+	}
 
-func (e *Executor) execute(session SessionInterface, packedMessage *[]byte) exchange.ResponseMessage {
+	var packed []byte
+	if e.jsonRpc {
+		packed = exchange.ExecuteJsonRpc(*packedMessage, e.batchConcurrency, call)
+	} else {
+		packed = exchange.Execute(*packedMessage, e.batchConcurrency, call)
+	}
 
-	var requestMessage exchange.RequestMessage
-	err := json.Unmarshal(*packedMessage, &requestMessage)
-	if err != nil {
-		return exchange.NewErrorResponse("", "WrongRequest", "can't parse message")
+	if packed == nil {
+		return nil, nil
 	}
 
-	requestId := requestMessage.Id
+	return &packed, nil
+}
 
-	switch requestMessage.Method {
+func (e *Executor) call(session SessionInterface, method string, rawParams json.RawMessage) (interface{}, *exchange.Error) {
+	switch method {
 
 	case "getBook":
 		var params GetBookParams
-		err := json.Unmarshal(requestMessage.Params, &params)
+		err := exchange.UnmarshalParams(rawParams, &params, "id")
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't parse params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("can't parse params: %v", err), nil)
 		}
 
 		err = params.Validate()
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't wrong params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %v", err), err)
 		}
 
-		result, err := e.handler.GetBook(session, params.Id)
-		if err != nil {
-			return exchange.NewErrorResponse(requestId, "ServerError", err.Error())
-		}
-
-		return exchange.NewResultResponse(requestId, result)
+		return e.invoke(&Call{Method: "getBook", Params: &params, Session: session}, func(call *Call) (interface{}, error) {
+			params := call.Params.(*GetBookParams)
+			return e.handler.GetBook(call.Session, params.Id)
+		})
 
 	case "getBooks":
 		var params GetBooksParams
-		err := json.Unmarshal(requestMessage.Params, &params)
+		err := exchange.UnmarshalParams(rawParams, &params, "id")
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't parse params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("can't parse params: %v", err), nil)
 		}
 
 		err = params.Validate()
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't wrong params: %v", err))
-		}
-
-		result, err := e.handler.GetBooks(session, params.Id)
-		if err != nil {
-			return exchange.NewErrorResponse(requestId, "ServerError", err.Error())
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %v", err), err)
 		}
 
-		return exchange.NewResultResponse(requestId, result)
+		return e.invoke(&Call{Method: "getBooks", Params: &params, Session: session}, func(call *Call) (interface{}, error) {
+			params := call.Params.(*GetBooksParams)
+			return e.handler.GetBooks(call.Session, params.Id)
+		})
 
 	case "getAuthor":
 		var params GetAuthorParams
-		err := json.Unmarshal(requestMessage.Params, &params)
+		err := exchange.UnmarshalParams(rawParams, &params, "id")
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't parse params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("can't parse params: %v", err), nil)
 		}
 
 		err = params.Validate()
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't wrong params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %v", err), err)
 		}
 
-		result, err := e.handler.GetAuthor(session, params.Id)
-		if err != nil {
-			return exchange.NewErrorResponse(requestId, "ServerError", err.Error())
-		}
-
-		return exchange.NewResultResponse(requestId, result)
+		return e.invoke(&Call{Method: "getAuthor", Params: &params, Session: session}, func(call *Call) (interface{}, error) {
+			params := call.Params.(*GetAuthorParams)
+			return e.handler.GetAuthor(call.Session, params.Id)
+		})
 
 	case "getAuthors":
 		var params GetAuthorsParams
-		err := json.Unmarshal(requestMessage.Params, &params)
+		err := exchange.UnmarshalParams(rawParams, &params, "id")
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't parse params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("can't parse params: %v", err), nil)
 		}
 
 		err = params.Validate()
 		if err != nil {
-			return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't wrong params: %v", err))
+			return nil, exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %v", err), err)
 		}
 
-		result, err := e.handler.GetAuthors(session, params.Id)
-		if err != nil {
-			return exchange.NewErrorResponse(requestId, "ServerError", err.Error())
+		return e.invoke(&Call{Method: "getAuthors", Params: &params, Session: session}, func(call *Call) (interface{}, error) {
+			params := call.Params.(*GetAuthorsParams)
+			return e.handler.GetAuthors(call.Session, params.Id)
+		})
+
+	}
+
+	return nil, exchange.NewMethodNotFoundError(method)
+}
+
+func (e *Executor) invoke(call *Call, handle CallHandler) (result interface{}, callErr *exchange.Error) {
+	defer func() {
+		recovered := recover()
+		if recovered != nil {
+			result, callErr = nil, e.hideError(call, exchange.NewPanicError(recovered))
 		}
+	}()
 
-		return exchange.NewResultResponse(requestId, result)
+	for index := len(e.interceptors) - 1; index >= 0; index-- {
+		handle = intercept(e.interceptors[index], handle)
+	}
+
+	result, err := handle(call)
+	if err != nil {
+		if publicErr, ok := err.(*exchange.Error); ok {
+			return nil, publicErr
+		}
 
+		return nil, e.hideError(call, err)
 	}
 
-	return exchange.NewErrorResponse("", "WrongRequest", "no such method")
+	return result, nil
+}
+
+func (e *Executor) hideError(call *Call, err error) *exchange.Error {
+	correlationId := exchange.NewCorrelationId()
+	e.reportError(call, correlationId, err)
+
+	return exchange.NewHiddenInternalError(correlationId)
+}
+
+func intercept(interceptor Interceptor, next CallHandler) CallHandler {
+	return func(call *Call) (interface{}, error) {
+		return interceptor(call, next)
+	}
 }
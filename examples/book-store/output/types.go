@@ -1,10 +1,10 @@
+// api service for books store
 package executor
 
 import (
 	"encoding/json"
-	"fmt"
+	"github.com/akaumov/go-service/exchange"
 	validator "github.com/asaskevich/govalidator"
-	"github.com/pkg/errors"
 )
 
 type Validatable interface {
@@ -22,67 +22,88 @@ const (
 )
 
 func (v BookType) Validate() error {
-	switch v {
-	case Magazine:
-		return nil
-	case BookItem:
-		return nil
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
 
+	if len(errs) > 0 {
+		return errs
 	}
 
-	return fmt.Errorf("no such value: %v", v)
+	return nil
+}
+
+func (v BookType) ValidateAt(path string, errs *exchange.ValidationErrors) {
+
+	switch v {
+	case Magazine, BookItem:
+		return
+	}
+
+	errs.Add(path, "enum", "must be one of: magazineItem, book", map[string]interface{}{"values": []BookType{Magazine, BookItem}})
+
 }
 
 /////////////////////////////////////////////////////////////////////
 //Book
 
 type Book struct {
-	Type      BookType `json:"type"`
 	Id        string   `json:"id"`
 	AuthorId  string   `json:"authorId"`
 	CreatedAt int64    `json:"createdAt"`
 	Title     string   `json:"title"`
+	Type      BookType `json:"type"`
 }
 
 func (v *Book) Validate() error {
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
 
-	{
-		value := v.Type
-		isValid := value.Validate() == nil
-
-		if !isValid {
-			return fmt.Errorf("Type is invalid")
-		}
+	if len(errs) > 0 {
+		return errs
 	}
 
+	return nil
+}
+
+func (v *Book) ValidateAt(path string, errs *exchange.ValidationErrors) {
+
 	{
 		value := v.Id
-		isValid := validator.IsUUID(value)
+		fieldPath := exchange.JoinPath(path, "id")
 
-		if !isValid {
-			return fmt.Errorf("Id is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
 	{
 		value := v.AuthorId
-		isValid := validator.IsUUID(value)
+		fieldPath := exchange.JoinPath(path, "authorId")
 
-		if !isValid {
-			return fmt.Errorf("AuthorId is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
 	{
 		value := v.Title
-		isValid := (len(value) >= 0 && len(value) <= 255)
+		fieldPath := exchange.JoinPath(path, "title")
 
-		if !isValid {
-			return fmt.Errorf("Title is invalid")
+		if !(len(value) <= 255) {
+			errs.Add(fieldPath, "maxLength", "must be at most 255 characters long", map[string]interface{}{"max": 255})
 		}
+
+	}
+
+	{
+		value := v.Type
+		fieldPath := exchange.JoinPath(path, "type")
+		value.ValidateAt(fieldPath, errs)
+
 	}
 
-	return nil
 }
 
 func (v Book) String() string {
@@ -98,51 +119,69 @@ func (v Book) String() string {
 //Author
 
 type Author struct {
-	Surname    string  `json:"surname"`
-	Patronymic *string `json:"patronymic"`
 	Id         string  `json:"id"`
 	Name       string  `json:"name"`
+	Surname    string  `json:"surname"`
+	Patronymic *string `json:"patronymic"`
 }
 
 func (v *Author) Validate() error {
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (v *Author) ValidateAt(path string, errs *exchange.ValidationErrors) {
 
 	{
-		value := v.Surname
-		isValid := (len(value) >= 0 && len(value) <= 255)
+		value := v.Id
+		fieldPath := exchange.JoinPath(path, "id")
 
-		if !isValid {
-			return fmt.Errorf("Surname is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
 	{
-		value := v.Patronymic
-		isValid := value == nil || (len(*value) >= 0 && len(*value) <= 255)
+		value := v.Name
+		fieldPath := exchange.JoinPath(path, "name")
 
-		if !isValid {
-			return fmt.Errorf("Patronymic is invalid")
+		if !(len(value) <= 255) {
+			errs.Add(fieldPath, "maxLength", "must be at most 255 characters long", map[string]interface{}{"max": 255})
 		}
+
 	}
 
 	{
-		value := v.Id
-		isValid := validator.IsUUID(value)
+		value := v.Surname
+		fieldPath := exchange.JoinPath(path, "surname")
 
-		if !isValid {
-			return fmt.Errorf("Id is invalid")
+		if !(len(value) <= 255) {
+			errs.Add(fieldPath, "maxLength", "must be at most 255 characters long", map[string]interface{}{"max": 255})
 		}
+
 	}
 
 	{
-		value := v.Name
-		isValid := (len(value) >= 0 && len(value) <= 255)
+		value := v.Patronymic
+		fieldPath := exchange.JoinPath(path, "patronymic")
+
+		if value != nil {
+
+			if !(len(*value) <= 255) {
+				errs.Add(fieldPath, "maxLength", "must be at most 255 characters long", map[string]interface{}{"max": 255})
+			}
 
-		if !isValid {
-			return fmt.Errorf("Name is invalid")
 		}
+
 	}
 
-	return nil
 }
 
 func (v Author) String() string {
@@ -165,17 +204,28 @@ type GetBookParams struct {
 }
 
 func (v *GetBookParams) Validate() error {
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (v *GetBookParams) ValidateAt(path string, errs *exchange.ValidationErrors) {
 
 	{
 		value := v.Id
-		isValid := validator.IsUUID(value)
+		fieldPath := exchange.JoinPath(path, "id")
 
-		if !isValid {
-			return fmt.Errorf("Id is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
-	return nil
 }
 
 func (v GetBookParams) String() string {
@@ -195,17 +245,28 @@ type GetBooksParams struct {
 }
 
 func (v *GetBooksParams) Validate() error {
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (v *GetBooksParams) ValidateAt(path string, errs *exchange.ValidationErrors) {
 
 	{
 		value := v.Id
-		isValid := validator.IsUUID(value)
+		fieldPath := exchange.JoinPath(path, "id")
 
-		if !isValid {
-			return fmt.Errorf("Id is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
-	return nil
 }
 
 func (v GetBooksParams) String() string {
@@ -225,17 +286,28 @@ type GetAuthorParams struct {
 }
 
 func (v *GetAuthorParams) Validate() error {
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (v *GetAuthorParams) ValidateAt(path string, errs *exchange.ValidationErrors) {
 
 	{
 		value := v.Id
-		isValid := validator.IsUUID(value)
+		fieldPath := exchange.JoinPath(path, "id")
 
-		if !isValid {
-			return fmt.Errorf("Id is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
-	return nil
 }
 
 func (v GetAuthorParams) String() string {
@@ -255,17 +327,28 @@ type GetAuthorsParams struct {
 }
 
 func (v *GetAuthorsParams) Validate() error {
+	errs := exchange.ValidationErrors{}
+	v.ValidateAt("", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (v *GetAuthorsParams) ValidateAt(path string, errs *exchange.ValidationErrors) {
 
 	{
 		value := v.Id
-		isValid := validator.IsUUID(value)
+		fieldPath := exchange.JoinPath(path, "id")
 
-		if !isValid {
-			return fmt.Errorf("Id is invalid")
+		if !(validator.IsUUID(value)) {
+			errs.Add(fieldPath, "uuid", "must be a valid uuid", nil)
 		}
+
 	}
 
-	return nil
 }
 
 func (v GetAuthorsParams) String() string {
@@ -0,0 +1,33 @@
+package exchange
+
+import (
+	"sync"
+)
+
+// runBatch calls run for every index of a batch with at most concurrency calls at
+// a time. A concurrency below 2 runs the batch sequentially.
+func runBatch(count int, concurrency int, run func(index int)) {
+	if concurrency < 2 {
+		for index := 0; index < count; index++ {
+			run(index)
+		}
+		return
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	var group sync.WaitGroup
+
+	for index := 0; index < count; index++ {
+		group.Add(1)
+		semaphore <- struct{}{}
+
+		go func(index int) {
+			defer group.Done()
+			defer func() { <-semaphore }()
+
+			run(index)
+		}(index)
+	}
+
+	group.Wait()
+}
@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		count         int
+		concurrency   int
+		maxConcurrent int
+	}{
+		{name: "empty", count: 0, concurrency: 4, maxConcurrent: 0},
+		{name: "sequential", count: 5, concurrency: 1, maxConcurrent: 1},
+		{name: "zero concurrency", count: 5, concurrency: 0, maxConcurrent: 1},
+		{name: "limited", count: 10, concurrency: 3, maxConcurrent: 3},
+		{name: "more slots than calls", count: 2, concurrency: 8, maxConcurrent: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			calls := make([]int, test.count)
+			running := 0
+			maxRunning := 0
+
+			runBatch(test.count, test.concurrency, func(index int) {
+				mutex.Lock()
+				calls[index]++
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mutex.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+			})
+
+			for index, count := range calls {
+				if count != 1 {
+					t.Errorf("index %v run %v times", index, count)
+				}
+			}
+
+			if maxRunning != test.maxConcurrent {
+				t.Errorf("%v calls ran at a time, want %v", maxRunning, test.maxConcurrent)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Transport sends a packed JSON-RPC 2.0 request or batch and returns the packed
+// response, nil if there is nothing to respond.
+type Transport interface {
+	RoundTrip(ctx context.Context, request []byte) ([]byte, error)
+}
+
+type TransportFunc func(ctx context.Context, request []byte) ([]byte, error)
+
+func (f TransportFunc) RoundTrip(ctx context.Context, request []byte) ([]byte, error) {
+	return f(ctx, request)
+}
+
+// LocalTransport runs requests with an executor of the same process, e.g. in tests.
+func LocalTransport(execute ExecuteFunc, session Session) Transport {
+	return TransportFunc(func(ctx context.Context, request []byte) ([]byte, error) {
+		return execute(ctx, session, request)
+	})
+}
+
+type clientResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"error"`
+}
+
+// Call calls method through transport and unpacks the result into result. An error
+// response is returned as *Error with Data holding the raw json.RawMessage. Only
+// JSON-RPC 2.0 is spoken, a response in the legacy envelope is an error.
+func Call(ctx context.Context, transport Transport, id uint64, method string, params interface{}, result interface{}) error {
+	request := JsonRpcRequest{
+		JsonRpc: JsonRpcVersion,
+		Id:      json.RawMessage(fmt.Sprintf("%d", id)),
+		Method:  method,
+	}
+
+	if params != nil {
+		packedParams, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("can't pack params: %v", err)
+		}
+		request.Params = packedParams
+	}
+
+	packedRequest, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("can't pack request: %v", err)
+	}
+
+	packedResponse, err := transport.RoundTrip(ctx, packedRequest)
+	if err != nil {
+		return err
+	}
+
+	if packedResponse == nil {
+		return fmt.Errorf("no response to %v", method)
+	}
+
+	var response clientResponse
+	err = json.Unmarshal(packedResponse, &response)
+	if err != nil {
+		return fmt.Errorf("can't parse response: %v", err)
+	}
+
+	if response.JsonRpc != JsonRpcVersion {
+		return fmt.Errorf("response to %v isn't JSON-RPC %v, the executor must be created with WithJsonRpc()", method, JsonRpcVersion)
+	}
+
+	if response.Error != nil {
+		var data interface{}
+		if len(response.Error.Data) > 0 {
+			data = response.Error.Data
+		}
+
+		return NewError("", response.Error.Code, response.Error.Message, data)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(response.Id), request.Id) {
+		return fmt.Errorf("response id %s doesn't match request id %s", response.Id, request.Id)
+	}
+
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+
+	err = json.Unmarshal(response.Result, result)
+	if err != nil {
+		return fmt.Errorf("can't parse result: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package exchange
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		result    string
+		errorCode int
+		errorText string
+	}{
+		{name: "result", response: `{"jsonrpc":"2.0","id":1,"result":"a"}`, result: "a"},
+		{name: "error", response: `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"no such method: echo"}}`, errorCode: MethodNotFoundCode},
+		{name: "legacy envelope", response: `{"id":"","result":null,"error":{"name":"WrongRequest","message":"can't parse message"}}`, errorText: "WithJsonRpc()"},
+		{name: "other id", response: `{"jsonrpc":"2.0","id":2,"result":"a"}`, errorText: "doesn't match"},
+		{name: "no response", errorText: "no response"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := TransportFunc(func(ctx context.Context, request []byte) ([]byte, error) {
+				if test.response == "" {
+					return nil, nil
+				}
+				return []byte(test.response), nil
+			})
+
+			var result string
+			err := Call(context.Background(), transport, 1, "echo", nil, &result)
+
+			switch {
+			case test.errorCode != 0:
+				callErr, ok := err.(*Error)
+				if !ok || callErr.Code != test.errorCode {
+					t.Errorf("error = %v, want code %v", err, test.errorCode)
+				}
+			case test.errorText != "":
+				if err == nil || !strings.Contains(err.Error(), test.errorText) {
+					t.Errorf("error = %v, want one containing %q", err, test.errorText)
+				}
+			default:
+				if err != nil || result != test.result {
+					t.Errorf("result, error = %q, %v, want %q", result, err, test.result)
+				}
+			}
+		})
+	}
+}
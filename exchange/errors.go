@@ -0,0 +1,118 @@
+package exchange
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+const (
+	ParseErrorCode     = -32700
+	InvalidRequestCode = -32600
+	MethodNotFoundCode = -32601
+	InvalidParamsCode  = -32602
+	InternalErrorCode  = -32603
+
+	TimeoutCode      = -32000
+	UnauthorizedCode = -32001
+)
+
+// Error is a failed call. Name is sent in the legacy envelope, Code in JSON-RPC 2.0.
+type Error struct {
+	Name    string
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(name string, code int, message string, data interface{}) *Error {
+	return &Error{
+		Name:    name,
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}
+
+func NewParseError(message string) *Error {
+	return NewError("WrongRequest", ParseErrorCode, message, nil)
+}
+
+func NewInvalidRequestError(message string) *Error {
+	return NewError("WrongRequest", InvalidRequestCode, message, nil)
+}
+
+func NewMethodNotFoundError(method string) *Error {
+	return NewError("WrongRequest", MethodNotFoundCode, fmt.Sprintf("no such method: %v", method), nil)
+}
+
+func NewInvalidParamsError(message string, data interface{}) *Error {
+	return NewError("WrongRequest", InvalidParamsCode, message, data)
+}
+
+func NewInternalError(message string) *Error {
+	return NewError("ServerError", InternalErrorCode, message, nil)
+}
+
+func NewErrorResponseFromError(requestId string, err *Error) ResponseMessage {
+	return NewErrorResponseWithData(requestId, err.Name, err.Message, err.Data)
+}
+
+func NewTimeoutError(message string) *Error {
+	return NewError("Timeout", TimeoutCode, message, nil)
+}
+
+func NewUnauthorizedError(message string) *Error {
+	return NewError("Unauthorized", UnauthorizedCode, message, nil)
+}
+
+// Public marks err as safe to show to clients: its message is sent as a ServerError.
+// Messages of other errors returned by handlers are reported and never sent.
+func Public(err error) *Error {
+	return NewError("ServerError", InternalErrorCode, err.Error(), nil)
+}
+
+// NewHiddenInternalError is sent instead of an error that isn't public. The
+// correlation id lets the error be found in the reports of the server.
+func NewHiddenInternalError(correlationId string) *Error {
+	return NewError(
+		"ServerError",
+		InternalErrorCode,
+		fmt.Sprintf("internal error, correlation id: %v", correlationId),
+		map[string]interface{}{"correlationId": correlationId},
+	)
+}
+
+func NewCorrelationId() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// PanicError is a recovered panic of a handler or an interceptor.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func NewPanicError(value interface{}) *PanicError {
+	return &PanicError{
+		Value: value,
+		Stack: debug.Stack(),
+	}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
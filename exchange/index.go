@@ -2,6 +2,7 @@ package exchange
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 type RequestMessage struct {
@@ -17,14 +18,20 @@ type ResponseMessage struct {
 }
 
 type ErrorResponse struct {
-	Name    string `json:"name"`
-	Message string `json:"message"`
+	Name    string      `json:"name"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 func NewErrorResponse(requestId string, name string, message string) ResponseMessage {
+	return NewErrorResponseWithData(requestId, name, message, nil)
+}
+
+func NewErrorResponseWithData(requestId string, name string, message string, data interface{}) ResponseMessage {
 	packed, _ := json.Marshal(ErrorResponse{
 		Name:    name,
 		Message: message,
+		Data:    data,
 	})
 
 	rawPacked := json.RawMessage(packed)
@@ -47,3 +54,53 @@ func NewResultResponse(requestId string, result interface{}) ResponseMessage {
 		Error:  nil,
 	}
 }
+
+// Execute runs a request or a batch of requests in the legacy envelope with call and
+// returns the packed response. Batch responses keep the order of the requests.
+func Execute(packedMessage []byte, concurrency int, call CallFunc) []byte {
+	var response interface{}
+
+	if firstByte(packedMessage) == '[' {
+		var requests []RequestMessage
+		err := json.Unmarshal(packedMessage, &requests)
+
+		switch {
+		case err != nil:
+			response = NewErrorResponse("", "WrongRequest", "can't parse message")
+
+		case len(requests) == 0:
+			response = NewErrorResponse("", "WrongRequest", "batch must not be empty")
+
+		default:
+			responses := make([]ResponseMessage, len(requests))
+			runBatch(len(requests), concurrency, func(index int) {
+				responses[index] = executeRequest(requests[index], call)
+			})
+			response = responses
+		}
+	} else {
+		var request RequestMessage
+		err := json.Unmarshal(packedMessage, &request)
+		if err != nil {
+			response = NewErrorResponse("", "WrongRequest", "can't parse message")
+		} else {
+			response = executeRequest(request, call)
+		}
+	}
+
+	packed, err := json.Marshal(response)
+	if err != nil {
+		packed, _ = json.Marshal(NewErrorResponse("", "ServerError", fmt.Sprintf("can't pack response: %v", err)))
+	}
+
+	return packed
+}
+
+func executeRequest(request RequestMessage, call CallFunc) ResponseMessage {
+	result, err := call(request.Method, request.Params)
+	if err != nil {
+		return NewErrorResponseFromError(request.Id, err)
+	}
+
+	return NewResultResponse(request.Id, result)
+}
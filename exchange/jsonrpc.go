@@ -0,0 +1,222 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const JsonRpcVersion = "2.0"
+
+type JsonRpcRequest struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request has no id, so it gets no response.
+// A request with "id": null is not a notification.
+func (r JsonRpcRequest) IsNotification() bool {
+	return len(r.Id) == 0
+}
+
+type JsonRpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type JsonRpcResponse struct {
+	JsonRpc string           `json:"jsonrpc"`
+	Id      json.RawMessage  `json:"id"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *JsonRpcError    `json:"error,omitempty"`
+}
+
+func NewJsonRpcResultResponse(requestId json.RawMessage, result interface{}) JsonRpcResponse {
+	packed, err := json.Marshal(result)
+	if err != nil {
+		return NewJsonRpcErrorResponse(requestId, NewInternalError(fmt.Sprintf("can't pack result: %v", err)))
+	}
+
+	rawPacked := json.RawMessage(packed)
+
+	return JsonRpcResponse{
+		JsonRpc: JsonRpcVersion,
+		Id:      requestId,
+		Result:  &rawPacked,
+	}
+}
+
+func NewJsonRpcErrorResponse(requestId json.RawMessage, err *Error) JsonRpcResponse {
+	return JsonRpcResponse{
+		JsonRpc: JsonRpcVersion,
+		Id:      requestId,
+		Error: &JsonRpcError{
+			Code:    err.Code,
+			Message: err.Message,
+			Data:    err.Data,
+		},
+	}
+}
+
+// ParseJsonRpcRequest checks the request envelope. On failure the returned request
+// still carries the id if it could be read, so the error can be addressed to it.
+func ParseJsonRpcRequest(packed []byte) (JsonRpcRequest, *Error) {
+	request := JsonRpcRequest{}
+
+	if !json.Valid(packed) {
+		return request, NewParseError("can't parse message")
+	}
+
+	var members map[string]json.RawMessage
+	err := json.Unmarshal(packed, &members)
+	if err != nil {
+		return request, NewInvalidRequestError("request must be an object")
+	}
+
+	if id, ok := members["id"]; ok {
+		if !isValidId(id) {
+			return request, NewInvalidRequestError("id must be a string, a number or null")
+		}
+		request.Id = id
+	}
+
+	err = json.Unmarshal(members["jsonrpc"], &request.JsonRpc)
+	if err != nil || request.JsonRpc != JsonRpcVersion {
+		return request, NewInvalidRequestError(`jsonrpc must be "2.0"`)
+	}
+
+	err = json.Unmarshal(members["method"], &request.Method)
+	if err != nil || request.Method == "" {
+		return request, NewInvalidRequestError("method must be a non-empty string")
+	}
+
+	if params, ok := members["params"]; ok {
+		switch firstByte(params) {
+		case '{', '[':
+			request.Params = params
+		default:
+			return request, NewInvalidRequestError("params must be an object or an array")
+		}
+	}
+
+	return request, nil
+}
+
+func isValidId(id json.RawMessage) bool {
+	switch firstByte(id) {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+
+	return false
+}
+
+func firstByte(value json.RawMessage) byte {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return 0
+	}
+
+	return trimmed[0]
+}
+
+type CallFunc func(method string, params json.RawMessage) (interface{}, *Error)
+
+// ExecuteJsonRpc runs a JSON-RPC 2.0 request or batch with call and returns the packed
+// response, or nil if there is nothing to respond (notifications only). Batch requests
+// are run with at most concurrency calls at a time.
+func ExecuteJsonRpc(packedMessage []byte, concurrency int, call CallFunc) []byte {
+	if firstByte(packedMessage) != '[' || !json.Valid(packedMessage) {
+		response := executeJsonRpcRequest(packedMessage, call)
+		if response == nil {
+			return nil
+		}
+
+		packed, _ := json.Marshal(response)
+		return packed
+	}
+
+	var requests []json.RawMessage
+	_ = json.Unmarshal(packedMessage, &requests)
+
+	if len(requests) == 0 {
+		packed, _ := json.Marshal(NewJsonRpcErrorResponse(nil, NewInvalidRequestError("batch must not be empty")))
+		return packed
+	}
+
+	responses := make([]*JsonRpcResponse, len(requests))
+	runBatch(len(requests), concurrency, func(index int) {
+		responses[index] = executeJsonRpcRequest(requests[index], call)
+	})
+
+	result := []*JsonRpcResponse{}
+	for _, response := range responses {
+		if response != nil {
+			result = append(result, response)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	packed, _ := json.Marshal(result)
+	return packed
+}
+
+func executeJsonRpcRequest(packedMessage []byte, call CallFunc) *JsonRpcResponse {
+	request, requestErr := ParseJsonRpcRequest(packedMessage)
+	if requestErr != nil {
+		response := NewJsonRpcErrorResponse(request.Id, requestErr)
+		return &response
+	}
+
+	result, callErr := call(request.Method, request.Params)
+	if request.IsNotification() {
+		return nil
+	}
+
+	var response JsonRpcResponse
+	if callErr != nil {
+		response = NewJsonRpcErrorResponse(request.Id, callErr)
+	} else {
+		response = NewJsonRpcResultResponse(request.Id, result)
+	}
+
+	return &response
+}
+
+// UnmarshalParams decodes params given by name (an object) or by position (an array
+// in the order of names) into target. Missing params decode as an empty object.
+func UnmarshalParams(params json.RawMessage, target interface{}, names ...string) error {
+	switch firstByte(params) {
+	case 0, 'n':
+		params = json.RawMessage("{}")
+
+	case '[':
+		var positional []json.RawMessage
+		err := json.Unmarshal(params, &positional)
+		if err != nil {
+			return err
+		}
+
+		if len(positional) > len(names) {
+			return fmt.Errorf("expected at most %v params, got %v", len(names), len(positional))
+		}
+
+		named := map[string]json.RawMessage{}
+		for index, value := range positional {
+			named[names[index]] = value
+		}
+
+		params, err = json.Marshal(named)
+		if err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(params, target)
+}
@@ -0,0 +1,185 @@
+package exchange
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJsonRpcRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		id        string
+		method    string
+		params    string
+		errorCode int
+	}{
+		{name: "named params", message: `{"jsonrpc":"2.0","id":1,"method":"getBook","params":{"id":"a"}}`, id: `1`, method: "getBook", params: `{"id":"a"}`},
+		{name: "positional params", message: `{"jsonrpc":"2.0","id":"x","method":"getBook","params":["a"]}`, id: `"x"`, method: "getBook", params: `["a"]`},
+		{name: "null id", message: `{"jsonrpc":"2.0","id":null,"method":"getBook"}`, id: `null`, method: "getBook"},
+		{name: "notification", message: `{"jsonrpc":"2.0","method":"getBook"}`, method: "getBook"},
+		{name: "invalid json", message: `{"jsonrpc":`, errorCode: ParseErrorCode},
+		{name: "not an object", message: `[1]`, errorCode: InvalidRequestCode},
+		{name: "object id", message: `{"jsonrpc":"2.0","id":{},"method":"getBook"}`, errorCode: InvalidRequestCode},
+		{name: "wrong version", message: `{"jsonrpc":"1.0","id":1,"method":"getBook"}`, id: `1`, errorCode: InvalidRequestCode},
+		{name: "missing method", message: `{"jsonrpc":"2.0","id":1}`, id: `1`, errorCode: InvalidRequestCode},
+		{name: "empty method", message: `{"jsonrpc":"2.0","id":1,"method":""}`, id: `1`, errorCode: InvalidRequestCode},
+		{name: "scalar params", message: `{"jsonrpc":"2.0","id":1,"method":"getBook","params":1}`, id: `1`, errorCode: InvalidRequestCode},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := ParseJsonRpcRequest([]byte(test.message))
+
+			if string(request.Id) != test.id {
+				t.Errorf("id = %s, want %s", request.Id, test.id)
+			}
+
+			if test.errorCode != 0 {
+				if err == nil || err.Code != test.errorCode {
+					t.Fatalf("error = %v, want code %v", err, test.errorCode)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if request.Method != test.method || string(request.Params) != test.params {
+				t.Errorf("method, params = %v, %s, want %v, %s", request.Method, request.Params, test.method, test.params)
+			}
+
+			if request.IsNotification() != (test.id == "") {
+				t.Errorf("IsNotification() = %v", request.IsNotification())
+			}
+		})
+	}
+}
+
+func echoCall(method string, params json.RawMessage) (interface{}, *Error) {
+	if method != "echo" {
+		return nil, NewMethodNotFoundError(method)
+	}
+
+	return params, nil
+}
+
+func TestExecuteJsonRpc(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		response string
+	}{
+		{
+			name:     "result",
+			message:  `{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`,
+			response: `{"jsonrpc":"2.0","id":1,"result":[1]}`,
+		},
+		{
+			name:     "call error",
+			message:  `{"jsonrpc":"2.0","id":1,"method":"unknown"}`,
+			response: `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"no such method: unknown"}}`,
+		},
+		{
+			name:     "parse error",
+			message:  `{"jsonrpc"`,
+			response: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"can't parse message"}}`,
+		},
+		{
+			name:    "notification",
+			message: `{"jsonrpc":"2.0","method":"echo"}`,
+		},
+		{
+			name:     "empty batch",
+			message:  `[]`,
+			response: `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch must not be empty"}}`,
+		},
+		{
+			name:     "batch",
+			message:  `[{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]},{"jsonrpc":"2.0","method":"echo"},1,{"jsonrpc":"2.0","id":2,"method":"echo","params":[2]}]`,
+			response: `[{"jsonrpc":"2.0","id":1,"result":[1]},{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"request must be an object"}},{"jsonrpc":"2.0","id":2,"result":[2]}]`,
+		},
+		{
+			name:    "batch of notifications",
+			message: `[{"jsonrpc":"2.0","method":"echo"},{"jsonrpc":"2.0","method":"echo"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, concurrency := range []int{1, 4} {
+				response := ExecuteJsonRpc([]byte(test.message), concurrency, echoCall)
+
+				if test.response == "" {
+					if response != nil {
+						t.Errorf("concurrency %v: response = %s, want none", concurrency, response)
+					}
+					continue
+				}
+
+				if !isSameJson(t, response, test.response) {
+					t.Errorf("concurrency %v: response = %s, want %s", concurrency, response, test.response)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalParams(t *testing.T) {
+	type params struct {
+		Id    string `json:"id"`
+		Limit int    `json:"limit"`
+	}
+
+	tests := []struct {
+		name    string
+		params  string
+		want    params
+		isError bool
+	}{
+		{name: "named", params: `{"id":"a","limit":2}`, want: params{Id: "a", Limit: 2}},
+		{name: "positional", params: `["a",2]`, want: params{Id: "a", Limit: 2}},
+		{name: "fewer positional", params: `["a"]`, want: params{Id: "a"}},
+		{name: "missing", params: ``, want: params{}},
+		{name: "null", params: `null`, want: params{}},
+		{name: "too many positional", params: `["a",2,3]`, isError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := params{}
+			err := UnmarshalParams(json.RawMessage(test.params), &got, "id", "limit")
+
+			if test.isError {
+				if err == nil {
+					t.Fatalf("no error, want one")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("params = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func isSameJson(t *testing.T, packed []byte, expected string) bool {
+	var got, want interface{}
+
+	if err := json.Unmarshal(packed, &got); err != nil {
+		t.Fatalf("can't parse %s: %v", packed, err)
+	}
+
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatalf("can't parse %s: %v", expected, err)
+	}
+
+	return reflect.DeepEqual(got, want)
+}
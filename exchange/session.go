@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"context"
+)
+
+// Session is the SessionInterface of generated executors.
+type Session interface {
+	GetUserId() string
+	GetSessionId() string
+}
+
+type StaticSession struct {
+	UserId    string
+	SessionId string
+}
+
+func (s StaticSession) GetUserId() string {
+	return s.UserId
+}
+
+func (s StaticSession) GetSessionId() string {
+	return s.SessionId
+}
+
+// Executor is implemented by generated executors.
+type Executor interface {
+	Execute(session Session, packedMessage *[]byte) (*[]byte, error)
+}
+
+// ContextExecutor is implemented by executors generated with the context option.
+type ContextExecutor interface {
+	Execute(ctx context.Context, session Session, packedMessage *[]byte) (*[]byte, error)
+}
+
+// ExecuteFunc runs a packed message with either kind of executor.
+type ExecuteFunc func(ctx context.Context, session Session, packedMessage []byte) ([]byte, error)
+
+func ExecutorFunc(executor Executor) ExecuteFunc {
+	return func(ctx context.Context, session Session, packedMessage []byte) ([]byte, error) {
+		return unpack(executor.Execute(session, &packedMessage))
+	}
+}
+
+func ContextExecutorFunc(executor ContextExecutor) ExecuteFunc {
+	return func(ctx context.Context, session Session, packedMessage []byte) ([]byte, error) {
+		return unpack(executor.Execute(ctx, session, &packedMessage))
+	}
+}
+
+func unpack(packed *[]byte, err error) ([]byte, error) {
+	if err != nil || packed == nil {
+		return nil, err
+	}
+
+	return *packed, nil
+}
@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ValidationError struct {
+	Path    string                 `json:"path"`
+	Rule    string                 `json:"rule"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%v %v", e.Path, e.Message)
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, validationError := range e {
+		messages = append(messages, validationError.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationErrors) Add(path string, rule string, message string, params map[string]interface{}) {
+	*e = append(*e, ValidationError{
+		Path:    path,
+		Rule:    rule,
+		Message: message,
+		Params:  params,
+	})
+}
+
+func JoinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func IndexPath(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
+
+var plainKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func KeyPath(path string, key string) string {
+	if plainKeyRegexp.MatchString(key) {
+		return JoinPath(path, key)
+	}
+
+	return path + "[" + strconv.Quote(key) + "]"
+}
@@ -0,0 +1,83 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Client sends requests to a Handler. It is an exchange.Transport for generated
+// clients.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	header     http.Header
+}
+
+type ClientOption func(c *Client)
+
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader adds header to every request, e.g. for authorization.
+func WithHeader(header http.Header) ClientOption {
+	return func(c *Client) {
+		for name, values := range header {
+			for _, value := range values {
+				c.header.Add(name, value)
+			}
+		}
+	}
+}
+
+func NewClient(url string, options ...ClientOption) *Client {
+	c := &Client{
+		url:        url,
+		httpClient: http.DefaultClient,
+		header:     http.Header{},
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+func (c *Client) RoundTrip(ctx context.Context, request []byte) ([]byte, error) {
+	httpRequest, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range c.header {
+		httpRequest.Header[name] = values
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := c.httpClient.Do(httpRequest.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResponse.Header.Get("Content-Type") != "application/json" {
+		return nil, fmt.Errorf("unexpected response %v: %s", httpResponse.Status, body)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,243 @@
+package httptransport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/akaumov/go-service/exchange"
+)
+
+const DefaultMaxBodySize = 1 << 20
+
+// responses smaller than that aren't worth compressing
+const gzipMinSize = 1024
+
+// SessionExtractor builds the session of a request. An error is answered with
+// 401 Unauthorized, its message is only sent for an *exchange.Error like the
+// ones of exchange.Public.
+type SessionExtractor func(r *http.Request) (exchange.Session, error)
+
+// StatusMapper returns the HTTP status of a JSON-RPC error code.
+type StatusMapper func(code int) int
+
+// Handler serves JSON-RPC 2.0 POST requests with an executor generated with
+// the WithJsonRpc option.
+type Handler struct {
+	execute        exchange.ExecuteFunc
+	extractSession SessionExtractor
+	statusOf       StatusMapper
+	maxBodySize    int64
+}
+
+type Option func(h *Handler)
+
+func WithSessionExtractor(extractor SessionExtractor) Option {
+	return func(h *Handler) {
+		h.extractSession = extractor
+	}
+}
+
+func WithStatusMapper(mapper StatusMapper) Option {
+	return func(h *Handler) {
+		h.statusOf = mapper
+	}
+}
+
+// WithMaxBodySize limits the size of request bodies, after decompression.
+func WithMaxBodySize(size int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = size
+	}
+}
+
+func NewHandler(executor exchange.Executor, options ...Option) *Handler {
+	return newHandler(exchange.ExecutorFunc(executor), options)
+}
+
+// NewContextHandler serves an executor generated with the context option. Handlers
+// get the context of the request, which is canceled when the client goes away.
+func NewContextHandler(executor exchange.ContextExecutor, options ...Option) *Handler {
+	return newHandler(exchange.ContextExecutorFunc(executor), options)
+}
+
+func newHandler(execute exchange.ExecuteFunc, options []Option) *Handler {
+	h := &Handler{
+		execute:        execute,
+		extractSession: anonymousSession,
+		statusOf:       DefaultStatus,
+		maxBodySize:    DefaultMaxBodySize,
+	}
+
+	for _, option := range options {
+		option(h)
+	}
+
+	return h
+}
+
+func anonymousSession(r *http.Request) (exchange.Session, error) {
+	return exchange.StaticSession{}, nil
+}
+
+// DefaultStatus answers malformed requests with 400, unknown methods with 404,
+// timeouts with 504 and internal errors with 500. Errors declared in the schema
+// are a part of the API and are answered with 200.
+func DefaultStatus(code int) int {
+	switch code {
+	case exchange.ParseErrorCode, exchange.InvalidRequestCode, exchange.InvalidParamsCode:
+		return http.StatusBadRequest
+	case exchange.MethodNotFoundCode:
+		return http.StatusNotFound
+	case exchange.InternalErrorCode:
+		return http.StatusInternalServerError
+	case exchange.TimeoutCode:
+		return http.StatusGatewayTimeout
+	case exchange.UnauthorizedCode:
+		return http.StatusUnauthorized
+	}
+
+	return http.StatusOK
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeError(w, r, http.StatusMethodNotAllowed, exchange.NewInvalidRequestError("only POST requests are accepted"))
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		h.writeError(w, r, http.StatusUnsupportedMediaType, exchange.NewInvalidRequestError("content type must be application/json"))
+		return
+	}
+
+	body, status, requestErr := h.readBody(r)
+	if requestErr != nil {
+		h.writeError(w, r, status, requestErr)
+		return
+	}
+
+	session, err := h.extractSession(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusUnauthorized, unauthorizedError(err))
+		return
+	}
+
+	response, err := h.execute(r.Context(), session, body)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, exchange.NewInternalError("can't execute request"))
+		return
+	}
+
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.write(w, r, h.responseStatus(response), response)
+}
+
+func unauthorizedError(err error) *exchange.Error {
+	if publicErr, ok := err.(*exchange.Error); ok {
+		return exchange.NewUnauthorizedError(publicErr.Message)
+	}
+
+	return exchange.NewUnauthorizedError("unauthorized")
+}
+
+func (h *Handler) readBody(r *http.Request) ([]byte, int, *exchange.Error) {
+	var reader io.Reader = r.Body
+
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(io.LimitReader(r.Body, h.maxBodySize+1))
+		if err != nil {
+			return nil, http.StatusBadRequest, exchange.NewParseError(fmt.Sprintf("can't decompress body: %v", err))
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	default:
+		return nil, http.StatusUnsupportedMediaType, exchange.NewInvalidRequestError("content encoding must be gzip or identity")
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(reader, h.maxBodySize+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, exchange.NewParseError(fmt.Sprintf("can't read body: %v", err))
+	}
+
+	if int64(len(body)) > h.maxBodySize {
+		return nil, http.StatusRequestEntityTooLarge, exchange.NewInvalidRequestError(fmt.Sprintf("body must not be larger than %v bytes", h.maxBodySize))
+	}
+
+	return body, http.StatusOK, nil
+}
+
+// responseStatus maps the error of a single response. Batches are answered with
+// 200 since their responses may have different outcomes.
+func (h *Handler) responseStatus(response []byte) int {
+	var single struct {
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(response), []byte("[")) {
+		return http.StatusOK
+	}
+
+	if json.Unmarshal(response, &single) != nil || single.Error == nil {
+		return http.StatusOK
+	}
+
+	return h.statusOf(single.Error.Code)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, err *exchange.Error) {
+	packed, _ := json.Marshal(exchange.NewJsonRpcErrorResponse(nil, err))
+	h.write(w, r, status, packed)
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, status int, packed []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if len(packed) < gzipMinSize || !acceptsGzip(r) {
+		w.WriteHeader(status)
+		w.Write(packed)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(status)
+
+	gzipWriter := gzip.NewWriter(w)
+	gzipWriter.Write(packed)
+	gzipWriter.Close()
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(strings.TrimSpace(encoding), ";")
+		if strings.ToLower(parts[0]) != "gzip" {
+			continue
+		}
+
+		if len(parts) > 1 && strings.Replace(strings.TrimSpace(parts[1]), " ", "", -1) == "q=0" {
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
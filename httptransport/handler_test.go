@@ -0,0 +1,254 @@
+package httptransport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akaumov/go-service/exchange"
+)
+
+// testExecutor answers JSON-RPC 2.0 requests: "echo" returns its params, "whoami"
+// the user id of the session and "fail" the error with the code given as the
+// only param.
+type testExecutor struct{}
+
+func (e testExecutor) Execute(session exchange.Session, packedMessage *[]byte) (*[]byte, error) {
+	response := exchange.ExecuteJsonRpc(*packedMessage, 1, func(method string, params json.RawMessage) (interface{}, *exchange.Error) {
+		switch method {
+		case "echo":
+			return params, nil
+		case "whoami":
+			return session.GetUserId(), nil
+		case "fail":
+			var code []int
+			_ = json.Unmarshal(params, &code)
+			return nil, exchange.NewError("Failed", code[0], "failed", nil)
+		}
+
+		return nil, exchange.NewMethodNotFoundError(method)
+	})
+
+	if response == nil {
+		return nil, nil
+	}
+
+	return &response, nil
+}
+
+func newTestRequest(method string, body string) *http.Request {
+	r := httptest.NewRequest(method, "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func gzipped(t *testing.T, body []byte) []byte {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestHandlerStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{name: "result", body: `{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}`, status: http.StatusOK},
+		{name: "declared error", body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":[1001]}`, status: http.StatusOK},
+		{name: "invalid params", body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":[-32602]}`, status: http.StatusBadRequest},
+		{name: "internal error", body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":[-32603]}`, status: http.StatusInternalServerError},
+		{name: "timeout", body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":[-32000]}`, status: http.StatusGatewayTimeout},
+		{name: "unknown method", body: `{"jsonrpc":"2.0","id":1,"method":"unknown"}`, status: http.StatusNotFound},
+		{name: "parse error", body: `{"jsonrpc"`, status: http.StatusBadRequest},
+		{name: "notification", body: `{"jsonrpc":"2.0","method":"echo"}`, status: http.StatusNoContent},
+		{name: "batch with errors", body: `[{"jsonrpc":"2.0","id":1,"method":"unknown"}]`, status: http.StatusOK},
+		{name: "GET", method: http.MethodGet, status: http.StatusMethodNotAllowed},
+		{name: "wrong content type", contentType: "text/plain", body: `{"jsonrpc":"2.0","id":1,"method":"echo"}`, status: http.StatusUnsupportedMediaType},
+	}
+
+	handler := NewHandler(testExecutor{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := http.MethodPost
+			if test.method != "" {
+				method = test.method
+			}
+
+			request := newTestRequest(method, test.body)
+			if test.contentType != "" {
+				request.Header.Set("Content-Type", test.contentType)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("status = %v, want %v, body %s", recorder.Code, test.status, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerStatusMapper(t *testing.T) {
+	handler := NewHandler(testExecutor{}, WithStatusMapper(func(code int) int {
+		if code == 1001 {
+			return http.StatusConflict
+		}
+		return DefaultStatus(code)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newTestRequest(http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"fail","params":[1001]}`))
+
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("status = %v, want %v", recorder.Code, http.StatusConflict)
+	}
+}
+
+func TestHandlerSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{name: "session", status: http.StatusOK},
+		{name: "hidden error", err: errors.New("token signature of user 42 is invalid"), status: http.StatusUnauthorized, message: "unauthorized"},
+		{name: "public error", err: exchange.Public(errors.New("token expired")), status: http.StatusUnauthorized, message: "token expired"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := NewHandler(testExecutor{}, WithSessionExtractor(func(r *http.Request) (exchange.Session, error) {
+				if test.err != nil {
+					return nil, test.err
+				}
+				return exchange.StaticSession{UserId: r.Header.Get("X-User")}, nil
+			}))
+
+			request := newTestRequest(http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"whoami"}`)
+			request.Header.Set("X-User", "42")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("status = %v, want %v", recorder.Code, test.status)
+			}
+
+			response := exchange.JsonRpcResponse{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("can't parse response %s: %v", recorder.Body, err)
+			}
+
+			if test.err == nil {
+				if response.Result == nil || string(*response.Result) != `"42"` {
+					t.Errorf("response = %s, want the user id", recorder.Body)
+				}
+				return
+			}
+
+			if response.Error == nil || response.Error.Code != exchange.UnauthorizedCode || response.Error.Message != test.message {
+				t.Errorf("response = %s, want message %q", recorder.Body, test.message)
+			}
+		})
+	}
+}
+
+func TestHandlerBodyLimit(t *testing.T) {
+	const maxBodySize = 256
+
+	small := []byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":["` + strings.Repeat("a", 100) + `"]}`)
+	large := []byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":["` + strings.Repeat("a", 1000) + `"]}`)
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		status   int
+	}{
+		{name: "within limit", body: small, status: http.StatusOK},
+		{name: "over limit", body: large, status: http.StatusRequestEntityTooLarge},
+		{name: "gzip within limit", body: gzipped(t, small), encoding: "gzip", status: http.StatusOK},
+		// compresses below the limit, the limit applies after decompression
+		{name: "gzip over limit", body: gzipped(t, large), encoding: "gzip", status: http.StatusRequestEntityTooLarge},
+		{name: "broken gzip", body: small, encoding: "gzip", status: http.StatusBadRequest},
+		{name: "unknown encoding", body: small, encoding: "br", status: http.StatusUnsupportedMediaType},
+	}
+
+	handler := NewHandler(testExecutor{}, WithMaxBodySize(maxBodySize))
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := newTestRequest(http.MethodPost, string(test.body))
+			request.Header.Set("Content-Encoding", test.encoding)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("status = %v, want %v, body %s", recorder.Code, test.status, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerCompressesLargeResponses(t *testing.T) {
+	tests := []struct {
+		name           string
+		size           int
+		acceptEncoding string
+		isCompressed   bool
+	}{
+		{name: "large", size: 2 * gzipMinSize, acceptEncoding: "gzip", isCompressed: true},
+		{name: "small", size: 10, acceptEncoding: "gzip", isCompressed: false},
+		{name: "not accepted", size: 2 * gzipMinSize, acceptEncoding: "gzip;q=0", isCompressed: false},
+	}
+
+	handler := NewHandler(testExecutor{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := newTestRequest(http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"echo","params":["`+strings.Repeat("a", test.size)+`"]}`)
+			request.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			isCompressed := recorder.Header().Get("Content-Encoding") == "gzip"
+			if isCompressed != test.isCompressed {
+				t.Fatalf("compressed = %v, want %v", isCompressed, test.isCompressed)
+			}
+
+			if !isCompressed {
+				return
+			}
+
+			reader, err := gzip.NewReader(recorder.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := exchange.JsonRpcResponse{}
+			if err := json.NewDecoder(reader).Decode(&response); err != nil || response.Result == nil {
+				t.Errorf("can't decode compressed response: %v", err)
+			}
+		})
+	}
+}
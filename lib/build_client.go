@@ -0,0 +1,181 @@
+package lib
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+func buildClientFile(service *Service) (string, error) {
+	methods := ""
+	for _, method := range service.Methods {
+		methods += buildClientMethod(method.Name, method.MethodData) + "\n"
+	}
+
+	decodeError := "return err"
+	if len(service.Errors) > 0 {
+		decodeError = buildClientErrorDecoder(service)
+	}
+
+	body := fmt.Sprintf(`
+		// Client calls the methods of the service through a transport, e.g.
+		// httptransport.NewClient, wstransport.Dial or exchange.LocalTransport.
+		// It speaks JSON-RPC 2.0, the executor must be created with WithJsonRpc().
+		type Client struct {
+			transport exchange.Transport
+			lastId uint64
+		}
+
+		func NewClient(transport exchange.Transport) *Client {
+			return &Client{
+				transport: transport,
+			}
+		}
+
+		func (c *Client) call(ctx context.Context, method string, params Validatable, result interface{}) error {
+			err := params.Validate()
+			if err != nil {
+				return exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %%v", err), err)
+			}
+
+			id := atomic.AddUint64(&c.lastId, 1)
+
+			err = exchange.Call(ctx, c.transport, id, method, params, result)
+			if callErr, ok := err.(*exchange.Error); ok {
+				return decodeError(callErr)
+			}
+
+			return err
+		}
+
+		// decodeError returns declared errors as their types.
+		func decodeError(err *exchange.Error) error {
+			%v
+		}
+
+		%v
+	`, decodeError, methods)
+
+	text := fmt.Sprintf(`
+		//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
+		package %v
+
+		%v
+
+		%v
+	`, service.Package, buildImports(
+		body,
+		append([]goImport{
+			{"context", "context"},
+			{"json", "encoding/json"},
+			{"fmt", "fmt"},
+			{"atomic", "sync/atomic"},
+			{"exchange", "github.com/akaumov/go-service/exchange"},
+		}, packageGoImports(service)...)...,
+	), body)
+
+	formattedText, err := format.Source([]byte(text))
+	if err != nil {
+		return "", fmt.Errorf("can't format code: %v \n\n %v", err, text)
+	}
+
+	return string(formattedText), nil
+}
+
+func buildClientErrorDecoder(service *Service) string {
+	cases := ""
+	for _, definition := range service.Errors {
+		cases += fmt.Sprintf(`
+			case %v:
+				declared = &%v{}
+		`, definition.Code, getErrorTypeName(definition.Name))
+	}
+
+	return fmt.Sprintf(`
+		var declared DeclaredError
+		switch err.Code {
+			%v
+		default:
+			return err
+		}
+
+		if data, ok := err.Data.(json.RawMessage); ok {
+			unpackErr := json.Unmarshal(data, declared)
+			if unpackErr != nil {
+				return err
+			}
+		}
+
+		return declared
+	`, cases)
+}
+
+// getArgumentNames returns the Go argument names of params. Names clashing with
+// Go keywords or with reserved, the other names of the generated function, get
+// an "Arg" suffix.
+func getArgumentNames(params []Parameter, reserved ...string) []string {
+	used := map[string]bool{}
+	for _, name := range reserved {
+		used[name] = true
+	}
+	for _, param := range params {
+		used[string(param.Name)] = true
+	}
+
+	names := []string{}
+	for _, param := range params {
+		name := string(param.Name)
+		if token.IsKeyword(name) || isReserved(name, reserved) {
+			name += "Arg"
+			for used[name] {
+				name += "_"
+			}
+			used[name] = true
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func isReserved(name string, reserved []string) bool {
+	for _, reservedName := range reserved {
+		if name == reservedName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func buildClientMethod(methodName MethodName, methodData MethodData) string {
+	paramsName := strings.Title(string(methodName)) + "Params"
+
+	resultTypeGo := getGoType(methodData.Result)
+	if isPassedByPointer(methodData.Result) {
+		resultTypeGo = "*" + resultTypeGo
+	}
+
+	params := ""
+	fields := ""
+	argumentNames := getArgumentNames(methodData.Params, "c", "ctx", "params", "result", "err")
+	for index, paramData := range methodData.Params {
+		params += fmt.Sprintf(", %v %v", argumentNames[index], getGoType(paramData.TypeInfo))
+		fields += fmt.Sprintf("%v: %v,\n", strings.Title(string(paramData.Name)), argumentNames[index])
+	}
+
+	return fmt.Sprintf(`
+		%vfunc (c *Client) %v(ctx context.Context%v) (%v, error) {
+			params := %v{
+				%v
+			}
+
+			var result %v
+			err := c.call(ctx, %v, &params, &result)
+			return result, err
+		}
+	`, getDocComment(methodData.Documentation), strings.Title(string(methodName)), params, resultTypeGo, paramsName, fields, resultTypeGo, strconv.Quote(string(methodName)))
+}
@@ -0,0 +1,859 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// The API reference is built as a list of blocks, then rendered to Markdown
+// and HTML.
+
+// docInline is a piece of text, a link when Anchor is set.
+type docInline struct {
+	Text   string
+	Code   bool
+	Anchor string
+}
+
+type docText []docInline
+
+type docHeading struct {
+	Level  int
+	Anchor string
+	Text   docText
+}
+
+type docParagraph docText
+
+type docList []docText
+
+type docTable struct {
+	Header []string
+	Rows   [][]docText
+}
+
+// docCode is a JSON example.
+type docCode string
+
+func plainText(text string) docText {
+	return docText{{Text: text}}
+}
+
+func codeText(text string) docText {
+	return docText{{Text: text, Code: true}}
+}
+
+func getMethodAnchor(name MethodName) string {
+	return "method-" + string(name)
+}
+
+func getTypeAnchor(name string) string {
+	return "type-" + name
+}
+
+func getErrorAnchor(name ErrorName) string {
+	return "error-" + string(name)
+}
+
+// builtinErrors are the errors of the executor every method may return.
+var builtinErrors = []struct {
+	name        string
+	codes       string
+	description string
+}{
+	{"WrongRequest", "-32700, -32600, -32601, -32602", "the request can't be parsed, the method doesn't exist or the params are invalid, data lists the violated constraints"},
+	{"Unauthorized", "-32001", "the call isn't allowed"},
+	{"Timeout", "-32000", "the method didn't finish in time"},
+	{"ServerError", "-32603", "the handler failed"},
+}
+
+type docsBuilder struct {
+	service *Service
+	blocks  []interface{}
+}
+
+func (b *docsBuilder) add(blocks ...interface{}) {
+	b.blocks = append(b.blocks, blocks...)
+}
+
+// typeText returns the type of typeInfo, custom types link to their section.
+func (b *docsBuilder) typeText(typeInfo TypeInfo) docText {
+	prefix := ""
+	switch {
+	case typeInfo.IsArray:
+		prefix = "[]"
+	case typeInfo.IsMap:
+		prefix = fmt.Sprintf("map[%v]", typeInfo.KeyType)
+	}
+
+	if !typeInfo.IsCustomType {
+		return codeText(prefix + typeInfo.DataType)
+	}
+
+	text := docText{}
+	if prefix != "" {
+		text = append(text, docInline{Text: prefix, Code: true})
+	}
+
+	return append(text, docInline{Text: typeInfo.DataType, Anchor: getTypeAnchor(typeInfo.DataType)})
+}
+
+func countConstraint(typeInfo TypeInfo, unit string) string {
+	switch {
+	case typeInfo.Min > 0 && typeInfo.Min == typeInfo.Max:
+		return fmt.Sprintf("exactly %v %v", typeInfo.Min, unit)
+	case typeInfo.Min > 0 && typeInfo.Max > 0:
+		return fmt.Sprintf("%v to %v %v", typeInfo.Min, typeInfo.Max, unit)
+	case typeInfo.Min > 0:
+		return fmt.Sprintf("at least %v %v", typeInfo.Min, unit)
+	case typeInfo.Max > 0:
+		return fmt.Sprintf("at most %v %v", typeInfo.Max, unit)
+	}
+
+	return ""
+}
+
+// constraintsText describes the constraints of typeInfo the validation checks.
+func (b *docsBuilder) constraintsText(typeInfo TypeInfo) docText {
+	constraints := []docText{}
+	addText := func(text string) {
+		if text != "" {
+			constraints = append(constraints, plainText(text))
+		}
+	}
+
+	switch {
+	case typeInfo.IsArray:
+		addText(countConstraint(typeInfo, "items"))
+	case typeInfo.IsMap:
+		addText(countConstraint(typeInfo, "entries"))
+		if description, ok := formatDescriptions[typeInfo.KeyType]; ok {
+			addText("keys must be " + description)
+		}
+	case builtinGoTypes[typeInfo.DataType] == "string":
+		addText(countConstraint(typeInfo, "characters"))
+	}
+
+	if description, ok := formatDescriptions[typeInfo.DataType]; ok {
+		addText(description)
+	}
+
+	if minimum := typeInfo.Minimum; minimum != nil {
+		if minimum.Exclusive {
+			addText(fmt.Sprintf("greater than %v", minimum.Value))
+		} else {
+			addText(fmt.Sprintf("at least %v", minimum.Value))
+		}
+	}
+
+	if maximum := typeInfo.Maximum; maximum != nil {
+		if maximum.Exclusive {
+			addText(fmt.Sprintf("less than %v", maximum.Value))
+		} else {
+			addText(fmt.Sprintf("at most %v", maximum.Value))
+		}
+	}
+
+	if typeInfo.MultipleOf > 0 {
+		addText(fmt.Sprintf("multiple of %v", typeInfo.MultipleOf))
+	}
+
+	if typeInfo.Pattern != "" {
+		constraints = append(constraints, docText{{Text: "matches "}, {Text: typeInfo.Pattern, Code: true}})
+	}
+
+	text := docText{}
+	for index, constraint := range constraints {
+		if index > 0 {
+			text = append(text, docInline{Text: ", "})
+		}
+		text = append(text, constraint...)
+	}
+
+	return text
+}
+
+func requiredText(typeInfo TypeInfo) docText {
+	if typeInfo.IsOptional {
+		return plainText("no")
+	}
+
+	return plainText("yes")
+}
+
+// documentationText returns the description of documentation on one line, so
+// it fits into a table cell, followed by the deprecation.
+func documentationText(documentation Documentation) docText {
+	text := docText{}
+	if documentation.Description != "" {
+		text = append(text, docInline{Text: strings.Join(strings.Fields(documentation.Description), " ")})
+	}
+
+	if documentation.IsDeprecated {
+		if len(text) > 0 {
+			text = append(text, docInline{Text: " "})
+		}
+
+		deprecation := "Deprecated."
+		if documentation.Deprecation != "" {
+			deprecation = "Deprecated: " + strings.Join(strings.Fields(documentation.Deprecation), " ")
+		}
+		text = append(text, docInline{Text: deprecation})
+	}
+
+	return text
+}
+
+func (b *docsBuilder) fieldsTable(fields StructTypeData) docTable {
+	table := docTable{Header: []string{"Name", "Type", "Required", "Constraints", "Description"}}
+
+	for _, field := range fields {
+		if !field.TypeInfo.IsVariable {
+			table.Rows = append(table.Rows, []docText{
+				codeText(string(field.Name)),
+				b.typeText(field.TypeInfo),
+				requiredText(field.TypeInfo),
+				b.constraintsText(field.TypeInfo),
+				documentationText(field.TypeInfo.Documentation),
+			})
+			continue
+		}
+
+		typeText := docText{}
+		constraintsText := docText{{Text: "selected by "}, {Text: string(field.TypeInfo.MapField), Code: true}, {Text: ": "}}
+		for index, mappingCase := range field.TypeInfo.Mapping {
+			if index > 0 {
+				typeText = append(typeText, docInline{Text: " or "})
+				constraintsText = append(constraintsText, docInline{Text: ", "})
+			}
+
+			typeText = append(typeText, b.typeText(mappingCase.TypeInfo)...)
+			constraintsText = append(constraintsText, docInline{Text: mappingCase.Value, Code: true})
+			constraintsText = append(constraintsText, docInline{Text: " → "})
+			constraintsText = append(constraintsText, b.typeText(mappingCase.TypeInfo)...)
+		}
+
+		table.Rows = append(table.Rows, []docText{
+			codeText(string(field.Name)),
+			typeText,
+			requiredText(field.TypeInfo),
+			constraintsText,
+			documentationText(field.TypeInfo.Documentation),
+		})
+	}
+
+	return table
+}
+
+func (b *docsBuilder) method(method Method) {
+	b.add(docHeading{Level: 3, Anchor: getMethodAnchor(method.Name), Text: codeText(string(method.Name))})
+
+	if text := documentationText(method.Documentation); len(text) > 0 {
+		b.add(docParagraph(text))
+	}
+
+	if len(method.Params) == 0 {
+		b.add(docParagraph(plainText("No params.")))
+	} else {
+		fields := StructTypeData{}
+		for _, param := range method.Params {
+			fields = append(fields, Field{Name: FieldName(param.Name), TypeInfo: param.TypeInfo})
+		}
+
+		b.add(b.fieldsTable(fields))
+	}
+
+	result := append(docText{{Text: "Result: "}}, b.typeText(method.Result)...)
+	if constraints := b.constraintsText(method.Result); len(constraints) > 0 {
+		result = append(append(result, docInline{Text: " ("}), constraints...)
+		result = append(result, docInline{Text: ")"})
+	}
+	if method.Result.IsOptional {
+		result = append(result, docInline{Text: ", may be null"})
+	}
+	b.add(docParagraph(result))
+
+	if method.Timeout > 0 {
+		b.add(docParagraph(plainText(fmt.Sprintf("Timeout: %v.", method.Timeout))))
+	}
+
+	if len(method.Errors) > 0 {
+		errorsText := docText{{Text: "Errors: "}}
+		for index, methodError := range method.Errors {
+			if index > 0 {
+				errorsText = append(errorsText, docInline{Text: ", "})
+			}
+			errorsText = append(errorsText, docInline{Text: string(methodError.Name), Anchor: getErrorAnchor(methodError.Name)})
+		}
+		b.add(docParagraph(errorsText))
+	}
+
+	params := docExampleObject{}
+	for _, param := range method.Params {
+		params = append(params, docExampleField{Name: string(param.Name), Value: b.example(param.TypeInfo, map[string]bool{})})
+	}
+
+	b.add(docParagraph(plainText("Request:")), exampleCode(struct {
+		Id     string           `json:"id"`
+		Method string           `json:"method"`
+		Params docExampleObject `json:"params"`
+	}{"1", string(method.Name), params}))
+
+	b.add(docParagraph(plainText("Response:")), exampleCode(struct {
+		Id     string      `json:"id"`
+		Result interface{} `json:"result"`
+		Error  interface{} `json:"error"`
+	}{"1", b.example(method.Result, map[string]bool{}), nil}))
+}
+
+func exampleCode(value interface{}) docCode {
+	packedValue, _ := json.MarshalIndent(value, "", "  ")
+	return docCode(packedValue)
+}
+
+func (b *docsBuilder) serviceType(serviceType serviceType) {
+	name := string(serviceType.Name)
+	b.add(docHeading{Level: 3, Anchor: getTypeAnchor(name), Text: plainText(name)})
+
+	if text := documentationText(serviceType.Documentation); len(text) > 0 {
+		b.add(docParagraph(text))
+	}
+
+	typeBuilder := &docsBuilder{service: serviceType.Service}
+
+	switch typeData := serviceType.Data.(type) {
+	case StructTypeData:
+		if len(typeData) == 0 {
+			b.add(docParagraph(plainText("No fields.")))
+			return
+		}
+
+		b.add(typeBuilder.fieldsTable(typeData))
+	case EnumTypeData:
+		b.add(docParagraph(plainText(fmt.Sprintf("Enum of %v values:", typeData.Type))))
+
+		table := docTable{Header: []string{"Name", "Value", "Description"}}
+		for _, value := range typeData.Values {
+			packedValue, _ := json.Marshal(enumExample(typeData, value))
+			table.Rows = append(table.Rows, []docText{plainText(value.Name), codeText(string(packedValue)), documentationText(value.Documentation)})
+		}
+		b.add(table)
+	}
+}
+
+func (b *docsBuilder) errors() {
+	b.add(docHeading{Level: 2, Anchor: "errors", Text: plainText("Errors")})
+	b.add(docParagraph(plainText("Errors are returned in the error of the response, by name in the legacy envelope and by code in JSON-RPC 2.0.")))
+
+	table := docTable{Header: []string{"Name", "Code", "Description"}}
+	for _, builtinError := range builtinErrors {
+		table.Rows = append(table.Rows, []docText{codeText(builtinError.name), plainText(builtinError.codes), plainText(builtinError.description)})
+	}
+	b.add(table)
+
+	for _, definition := range b.service.Errors {
+		b.add(docHeading{Level: 3, Anchor: getErrorAnchor(definition.Name), Text: plainText(string(definition.Name))})
+
+		description := fmt.Sprintf("Code %v", definition.Code)
+		if definition.Message != "" {
+			description += fmt.Sprintf(", message %q", definition.Message)
+		}
+		b.add(docParagraph(plainText(description + ".")))
+
+		if len(definition.Data) > 0 {
+			b.add(b.fieldsTable(definition.Data))
+		}
+
+		message := definition.Message
+		if message == "" {
+			message = string(definition.Name)
+		}
+
+		var data interface{}
+		if len(definition.Data) > 0 {
+			object := b.structExample(definition.Data, map[string]bool{})
+			for _, field := range object {
+				message = strings.Replace(message, "{"+field.Name+"}", fmt.Sprint(field.Value), -1)
+			}
+			data = object
+		}
+
+		b.add(docParagraph(plainText("Response:")), exampleCode(struct {
+			Id     string      `json:"id"`
+			Result interface{} `json:"result"`
+			Error  interface{} `json:"error"`
+		}{"1", nil, struct {
+			Name    string      `json:"name"`
+			Message string      `json:"message"`
+			Data    interface{} `json:"data,omitempty"`
+		}{string(definition.Name), message, data}}))
+	}
+}
+
+// buildDocs returns the blocks of the API reference of service.
+func buildDocs(service *Service) ([]interface{}, string, error) {
+	types, err := allTypes(service)
+	if err != nil {
+		return nil, "", err
+	}
+
+	title := service.Name
+	if title == "" {
+		title = service.Package
+	}
+
+	builder := &docsBuilder{service: service}
+	builder.add(docHeading{Level: 1, Text: plainText(title)})
+
+	if service.Version != "" {
+		builder.add(docParagraph(plainText("Version " + service.Version)))
+	}
+
+	if service.Description != "" {
+		builder.add(docParagraph(plainText(service.Description)))
+	}
+
+	builder.add(docParagraph(plainText("Requests and responses are shown in the legacy envelope, JSON-RPC 2.0 requests carry the same method and params.")))
+
+	contents := docList{}
+	for _, method := range service.Methods {
+		contents = append(contents, docText{{Text: string(method.Name), Anchor: getMethodAnchor(method.Name)}})
+	}
+	builder.add(docHeading{Level: 2, Anchor: "methods", Text: plainText("Methods")}, contents)
+
+	for _, method := range service.Methods {
+		builder.method(method)
+	}
+
+	if len(types) > 0 {
+		contents = docList{}
+		for _, serviceType := range types {
+			contents = append(contents, docText{{Text: string(serviceType.Name), Anchor: getTypeAnchor(string(serviceType.Name))}})
+		}
+		builder.add(docHeading{Level: 2, Anchor: "types", Text: plainText("Types")}, contents)
+
+		for _, serviceType := range types {
+			builder.serviceType(serviceType)
+		}
+	}
+
+	builder.errors()
+
+	return builder.blocks, title, nil
+}
+
+// docExampleObject keeps the order of the fields in example JSON.
+type docExampleObject []docExampleField
+
+type docExampleField struct {
+	Name  string
+	Value interface{}
+}
+
+func (o docExampleObject) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+	for index, field := range o {
+		if index > 0 {
+			buffer.WriteString(",")
+		}
+
+		packedValue, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.WriteString(strconv.Quote(field.Name))
+		buffer.WriteString(":")
+		buffer.Write(packedValue)
+	}
+	buffer.WriteString("}")
+
+	return buffer.Bytes(), nil
+}
+
+var exampleStrings = map[string]string{
+	"uuid":     "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"email":    "user@example.com",
+	"url":      "https://example.com",
+	"ipv4":     "192.0.2.1",
+	"ipv6":     "2001:db8::1",
+	"hostname": "example.com",
+	"date":     "2006-01-02",
+	"phone":    "+15550100200",
+	"hex":      "0f",
+	"base64":   "aGVsbG8=",
+}
+
+var exampleMapKeys = map[string]string{
+	"string": "key",
+	"int":    "1",
+	"int64":  "1",
+}
+
+func enumExample(data EnumTypeData, value EnumValue) interface{} {
+	if data.Type == "int" {
+		return value.IntegerValue
+	}
+
+	return value.StringValue
+}
+
+// example returns a value of typeInfo for the examples, visiting marks the
+// struct types being built to cut recursion.
+func (b *docsBuilder) example(typeInfo TypeInfo, visiting map[string]bool) interface{} {
+	switch {
+	case typeInfo.IsArray:
+		itemTypeInfo := typeInfo
+		itemTypeInfo.IsArray = false
+		itemTypeInfo.Min = 0
+		itemTypeInfo.Max = -1
+
+		if typeInfo.IsCustomType && visiting[typeInfo.DataType] {
+			return []interface{}{}
+		}
+
+		return []interface{}{b.example(itemTypeInfo, visiting)}
+	case typeInfo.IsMap:
+		itemTypeInfo := typeInfo
+		itemTypeInfo.IsMap = false
+		itemTypeInfo.Min = 0
+		itemTypeInfo.Max = -1
+
+		if typeInfo.IsCustomType && visiting[typeInfo.DataType] {
+			return map[string]interface{}{}
+		}
+
+		key, ok := exampleStrings[typeInfo.KeyType]
+		if !ok {
+			key = exampleMapKeys[typeInfo.KeyType]
+		}
+
+		return map[string]interface{}{key: b.example(itemTypeInfo, visiting)}
+	case typeInfo.IsCustomType:
+		return b.customExample(typeInfo, visiting)
+	}
+
+	switch builtinGoTypes[typeInfo.DataType] {
+	case "bool":
+		return true
+	case "int", "int64":
+		return numberExample(typeInfo)
+	}
+
+	if value, ok := exampleStrings[typeInfo.DataType]; ok {
+		return value
+	}
+
+	value := typeInfo.DataType
+	if typeInfo.Min > len(value) {
+		value += strings.Repeat("x", typeInfo.Min-len(value))
+	}
+	if typeInfo.Max > 0 && typeInfo.Max < len(value) {
+		value = value[:typeInfo.Max]
+	}
+
+	return value
+}
+
+func numberExample(typeInfo TypeInfo) int64 {
+	value := int64(1)
+	if minimum := typeInfo.Minimum; minimum != nil {
+		value = minimum.Value
+		if minimum.Exclusive {
+			value++
+		}
+	}
+
+	if typeInfo.MultipleOf > 0 && value%typeInfo.MultipleOf != 0 {
+		value += typeInfo.MultipleOf - value%typeInfo.MultipleOf
+		if value < 0 {
+			value -= typeInfo.MultipleOf
+		}
+	}
+
+	if maximum := typeInfo.Maximum; maximum != nil && typeInfo.Minimum == nil {
+		limit := maximum.Value
+		if maximum.Exclusive {
+			limit--
+		}
+
+		if value > limit {
+			value = limit
+			if typeInfo.MultipleOf > 0 {
+				value -= ((value % typeInfo.MultipleOf) + typeInfo.MultipleOf) % typeInfo.MultipleOf
+			}
+		}
+	}
+
+	return value
+}
+
+func (b *docsBuilder) customExample(typeInfo TypeInfo, visiting map[string]bool) interface{} {
+	typeData, ok := b.service.lookupType(typeInfo)
+	if !ok {
+		return nil
+	}
+
+	switch typeData := typeData.(type) {
+	case EnumTypeData:
+		if len(typeData.Values) == 0 {
+			return nil
+		}
+
+		return enumExample(typeData, typeData.Values[0])
+	case StructTypeData:
+		if visiting[typeInfo.DataType] {
+			return nil
+		}
+
+		visiting[typeInfo.DataType] = true
+		defer delete(visiting, typeInfo.DataType)
+
+		schemaImport, _ := b.service.importByNamespace(typeInfo.Namespace)
+		if schemaImport != nil && schemaImport.Service != nil {
+			return (&docsBuilder{service: schemaImport.Service}).structExample(typeData, visiting)
+		}
+
+		return b.structExample(typeData, visiting)
+	}
+
+	return nil
+}
+
+// structExample fills variable fields with their first mapping and sets
+// their mapField to its value.
+func (b *docsBuilder) structExample(data StructTypeData, visiting map[string]bool) docExampleObject {
+	object := docExampleObject{}
+	mapFieldValues := map[FieldName]interface{}{}
+
+	for _, field := range data {
+		if !field.TypeInfo.IsVariable {
+			continue
+		}
+
+		if len(field.TypeInfo.Mapping) == 0 {
+			continue
+		}
+
+		mapField, ok := findField(data, field.TypeInfo.MapField)
+		if ok {
+			mapFieldValues[mapField.Name] = discriminatorValue(b.service, mapField.TypeInfo, field.TypeInfo.Mapping[0])
+		}
+	}
+
+	for _, field := range data {
+		var value interface{}
+
+		switch {
+		case field.TypeInfo.IsVariable:
+			if len(field.TypeInfo.Mapping) > 0 {
+				value = b.example(field.TypeInfo.Mapping[0].TypeInfo, visiting)
+			}
+		case mapFieldValues[field.Name] != nil:
+			value = mapFieldValues[field.Name]
+		default:
+			value = b.example(field.TypeInfo, visiting)
+		}
+
+		object = append(object, docExampleField{Name: string(field.Name), Value: value})
+	}
+
+	return object
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `&lt;`,
+	"|", `\|`,
+)
+
+// markdown returns the text in Markdown, pipes of code are escaped in table
+// cells only.
+func (t docText) markdown(inTable bool) string {
+	text := ""
+	for _, inline := range t {
+		value := markdownEscaper.Replace(inline.Text)
+		if inline.Code {
+			code := inline.Text
+			if inTable {
+				code = strings.Replace(code, "|", `\|`, -1)
+			}
+
+			fence := "`"
+			for strings.Contains(code, fence) {
+				fence += "`"
+			}
+
+			if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+				code = " " + code + " "
+			}
+
+			value = fence + code + fence
+		}
+
+		if inline.Anchor != "" {
+			value = fmt.Sprintf("[%v](#%v)", value, inline.Anchor)
+		}
+
+		text += value
+	}
+
+	return text
+}
+
+func renderMarkdown(blocks []interface{}) string {
+	text := `<!-- GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE -->` + "\n"
+
+	for _, block := range blocks {
+		text += "\n"
+
+		switch block := block.(type) {
+		case docHeading:
+			if block.Anchor != "" {
+				text += fmt.Sprintf("<a name=\"%v\"></a>\n\n", block.Anchor)
+			}
+			text += fmt.Sprintf("%v %v\n", strings.Repeat("#", block.Level), block.Text.markdown(false))
+		case docParagraph:
+			text += docText(block).markdown(false) + "\n"
+		case docList:
+			for _, item := range block {
+				text += fmt.Sprintf("- %v\n", item.markdown(false))
+			}
+		case docTable:
+			text += "| " + strings.Join(block.Header, " | ") + " |\n"
+			text += strings.Repeat("| --- ", len(block.Header)) + "|\n"
+			for _, row := range block.Rows {
+				cells := []string{}
+				for _, cell := range row {
+					cells = append(cells, cell.markdown(true))
+				}
+				text += "| " + strings.Join(cells, " | ") + " |\n"
+			}
+		case docCode:
+			text += "```json\n" + string(block) + "\n```\n"
+		}
+	}
+
+	return text
+}
+
+func (t docText) html() string {
+	text := ""
+	for _, inline := range t {
+		value := html.EscapeString(inline.Text)
+		if inline.Code {
+			value = "<code>" + value + "</code>"
+		}
+
+		if inline.Anchor != "" {
+			value = fmt.Sprintf(`<a href="#%v">%v</a>`, html.EscapeString(inline.Anchor), value)
+		}
+
+		text += value
+	}
+
+	return text
+}
+
+// codeEscaper keeps the quotes of JSON examples readable.
+var codeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+const docsStyle = `
+body { margin: 0; font: 15px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292e; }
+nav { position: fixed; top: 0; bottom: 0; left: 0; width: 240px; overflow-y: auto; padding: 16px; box-sizing: border-box; background: #f6f8fa; border-right: 1px solid #e1e4e8; }
+nav a { display: block; color: #0366d6; text-decoration: none; padding: 2px 0; }
+nav h2 { font-size: 13px; text-transform: uppercase; color: #6a737d; margin: 16px 0 4px; }
+main { margin-left: 240px; padding: 16px 32px; max-width: 960px; }
+a { color: #0366d6; }
+h3 { margin-top: 32px; padding-top: 16px; border-top: 1px solid #e1e4e8; }
+table { border-collapse: collapse; margin: 8px 0; }
+th, td { border: 1px solid #dfe2e5; padding: 4px 10px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+code { font: 13px SFMono-Regular, Consolas, Menlo, monospace; background: #f3f4f6; padding: 1px 4px; border-radius: 3px; }
+pre { background: #f6f8fa; padding: 12px; overflow-x: auto; border-radius: 4px; }
+pre code { background: none; padding: 0; }
+`
+
+func renderHtml(title string, blocks []interface{}) string {
+	navigation := ""
+	content := ""
+
+	for _, block := range blocks {
+		switch block := block.(type) {
+		case docHeading:
+			anchor := ""
+			if block.Anchor != "" {
+				anchor = fmt.Sprintf(` id="%v"`, html.EscapeString(block.Anchor))
+			}
+			content += fmt.Sprintf("<h%v%v>%v</h%v>\n", block.Level, anchor, block.Text.html(), block.Level)
+
+			switch block.Level {
+			case 2:
+				navigation += fmt.Sprintf("<h2>%v</h2>\n", block.Text.html())
+			case 3:
+				navigation += fmt.Sprintf("<a href=\"#%v\">%v</a>\n", html.EscapeString(block.Anchor), block.Text.html())
+			}
+		case docParagraph:
+			content += fmt.Sprintf("<p>%v</p>\n", docText(block).html())
+		case docList:
+			// the navigation lists the methods and types already
+		case docTable:
+			content += "<table>\n<tr>"
+			for _, header := range block.Header {
+				content += fmt.Sprintf("<th>%v</th>", html.EscapeString(header))
+			}
+			content += "</tr>\n"
+
+			for _, row := range block.Rows {
+				content += "<tr>"
+				for _, cell := range row {
+					content += fmt.Sprintf("<td>%v</td>", cell.html())
+				}
+				content += "</tr>\n"
+			}
+			content += "</table>\n"
+		case docCode:
+			content += fmt.Sprintf("<pre><code>%v</code></pre>\n", codeEscaper.Replace(string(block)))
+		}
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<!-- GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE -->
+<html>
+<head>
+<meta charset="utf-8">
+<title>%v</title>
+<style>%v</style>
+</head>
+<body>
+<nav>
+%v</nav>
+<main>
+%v</main>
+</body>
+</html>
+`, html.EscapeString(title), docsStyle, navigation, content)
+}
+
+// buildDocsFiles returns the Markdown and HTML API reference of service by
+// file name.
+func buildDocsFiles(service *Service) (map[string]string, error) {
+	blocks, title, err := buildDocs(service)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"api.md":   renderMarkdown(blocks),
+		"api.html": renderHtml(title, blocks),
+	}, nil
+}
@@ -0,0 +1,158 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func getErrorTypeName(name ErrorName) string {
+	typeName := strings.Title(string(name))
+	if strings.HasSuffix(typeName, "Error") {
+		return typeName
+	}
+
+	return typeName + "Error"
+}
+
+func buildErrorsSection(service *Service) string {
+	if len(service.Errors) == 0 {
+		return ""
+	}
+
+	text := `
+		/////////////////////////////////////////////////////////////////////
+		//ERRORS
+
+		// DeclaredError is implemented by the errors declared in the schema. The
+		// executor sends them to clients if the method declares them.
+		type DeclaredError interface {
+			error
+			ErrorName() string
+			ExchangeError() *exchange.Error
+		}
+
+		// declaredError returns the *exchange.Error sent for err if the method
+		// declares it. Other declared errors, also those already converted with
+		// ExchangeError(), are hidden like any other non-public error.
+		func declaredError(err error, names ...string) error {
+			var name string
+			switch typedErr := err.(type) {
+			case DeclaredError:
+				name = typedErr.ErrorName()
+			case *exchange.Error:
+				if !declaredErrorNames[typedErr.Name] {
+					return err
+				}
+				name = typedErr.Name
+			default:
+				return err
+			}
+
+			for _, declaredName := range names {
+				if name != declaredName {
+					continue
+				}
+
+				if declared, ok := err.(DeclaredError); ok {
+					return declared.ExchangeError()
+				}
+				return err
+			}
+
+			return fmt.Errorf("error %v is not declared by the method: %v", name, err.Error())
+		}
+	`
+
+	names := ""
+	for _, definition := range service.Errors {
+		names += fmt.Sprintf("%q: true,\n", definition.Name)
+	}
+
+	text += fmt.Sprintf(`
+		var declaredErrorNames = map[string]bool{
+			%v
+		}
+	`, names)
+
+	for _, definition := range service.Errors {
+		text += fmt.Sprintf(`
+			/////////////////////////////////////////////////////////////////////
+			//%v
+			%v
+		`, definition.Name, buildErrorType(definition))
+	}
+
+	return text
+}
+
+func buildErrorType(definition ErrorDefinition) string {
+	typeName := getErrorTypeName(definition.Name)
+
+	fieldsText := ""
+	constructorParams := ""
+	constructorFields := ""
+	for _, field := range definition.Data {
+		fieldName := string(field.Name)
+		goType := getGoType(field.TypeInfo)
+
+		fieldsText += fmt.Sprintf("%v %v `json:\"%v\"`\n", strings.Title(fieldName), goType, fieldName)
+		constructorFields += fmt.Sprintf("%v: %v,\n", strings.Title(fieldName), fieldName)
+
+		if constructorParams != "" {
+			constructorParams += ", "
+		}
+		constructorParams += fmt.Sprintf("%v %v", fieldName, goType)
+	}
+
+	data := "e"
+	if len(definition.Data) == 0 {
+		data = "nil"
+	}
+
+	return fmt.Sprintf(`
+		type %[1]v struct {
+			%[2]v
+		}
+
+		func New%[1]v(%[3]v) *%[1]v {
+			return &%[1]v{
+				%[4]v
+			}
+		}
+
+		func (e *%[1]v) Error() string {
+			return %[5]v
+		}
+
+		func (e *%[1]v) ErrorName() string {
+			return %[6]q
+		}
+
+		func (e *%[1]v) ExchangeError() *exchange.Error {
+			return exchange.NewError(%[6]q, %[7]v, e.Error(), %[8]v)
+		}
+	`, typeName, fieldsText, constructorParams, constructorFields, getErrorMessage(definition), string(definition.Name), definition.Code, data)
+}
+
+// getErrorMessage returns the expression of the message of definition with the
+// {field} placeholders replaced by the values of the fields.
+func getErrorMessage(definition ErrorDefinition) string {
+	message := definition.Message
+	if message == "" {
+		message = string(definition.Name)
+	}
+
+	args := []string{}
+	format := messagePlaceholderRegexp.ReplaceAllStringFunc(strings.Replace(message, "%", "%%", -1), func(placeholder string) string {
+		fieldName := messagePlaceholderRegexp.FindStringSubmatch(placeholder)[1]
+		args = append(args, "e."+strings.Title(fieldName))
+		return "%v"
+	})
+
+	if len(args) == 0 {
+		return strconv.Quote(message)
+	}
+
+	return fmt.Sprintf("fmt.Sprintf(%v, %v)", strconv.Quote(format), strings.Join(args, ", "))
+}
@@ -3,69 +3,196 @@ package lib
 import (
 	"fmt"
 	"go/format"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func buildExecutorFile(service *Service) (string, error) {
+func buildExecutorFile(service *Service, options BuildOptions) (string, error) {
 	cases := ""
-	for method, methodData := range service.Methods {
-		cases += buildExecutorCase(method, methodData) + "\n"
+	for _, method := range service.Methods {
+		cases += buildExecutorCase(method.Name, method.MethodData, len(service.Errors) > 0, options) + "\n"
 	}
 
-	text := fmt.Sprintf(`
-		//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
-		package %v
-		
-		import (
-			"encoding/json"
-			"fmt"
-			"github.com/pkg/errors"
-
-			"github.com/akaumov/go-service/exchange"
-		)
+	sessionParams := "session SessionInterface"
+	sessionArgs := "session"
+	callContext := ""
+	if options.Context {
+		sessionParams = "ctx context.Context, session SessionInterface"
+		sessionArgs = "ctx, session"
+		callContext = "Context context.Context"
+	}
 
+	body := fmt.Sprintf(`
 		type Executor struct {
 			handler HandlerInterface
+			jsonRpc bool
+			batchConcurrency int
+			interceptors []Interceptor
+			reportError ErrorReporter
+		}
+
+		type SessionInterface = exchange.Session
+
+		type ExecutorOption func(e *Executor)
+
+		// WithJsonRpc makes the executor speak JSON-RPC 2.0 instead of the legacy envelope.
+		func WithJsonRpc() ExecutorOption {
+			return func(e *Executor) {
+				e.jsonRpc = true
+			}
+		}
+
+		// WithBatchConcurrency runs up to limit requests of a batch concurrently.
+		// Batches are run sequentially by default.
+		func WithBatchConcurrency(limit int) ExecutorOption {
+			return func(e *Executor) {
+				e.batchConcurrency = limit
+			}
+		}
+
+		// Call is a handler call seen by interceptors. Params holds the decoded and
+		// validated params of the method, e.g. *GetBookParams.
+		type Call struct {
+			Method string
+			Params interface{}
+			Session SessionInterface
+			%[4]v
 		}
 
-		type SessionInterface interface {
-			GetUserId() string
-			GetSessionId() string
+		type CallHandler func(call *Call) (interface{}, error)
+
+		// Interceptor wraps handler calls. It may inspect or change the call, return
+		// without calling next or rewrite the result and the error of next.
+		type Interceptor func(call *Call, next CallHandler) (interface{}, error)
+
+		// WithInterceptors adds interceptors to the executor. The first interceptor
+		// is the outermost one: it is called first and gets the result last.
+		func WithInterceptors(interceptors ...Interceptor) ExecutorOption {
+			return func(e *Executor) {
+				e.interceptors = append(e.interceptors, interceptors...)
+			}
 		}
 
-		func NewExecutor(handler HandlerInterface) *Executor {
-			return &Executor{
+		// ErrorReporter gets the errors that are hidden from clients: errors of handlers
+		// and interceptors that aren't *exchange.Error and recovered panics
+		// (*exchange.PanicError). correlationId is sent to the client instead.
+		type ErrorReporter func(call *Call, correlationId string, err error)
+
+		// WithErrorReporter replaces the default reporter, which logs through the log package.
+		func WithErrorReporter(reporter ErrorReporter) ExecutorOption {
+			return func(e *Executor) {
+				e.reportError = reporter
+			}
+		}
+
+		func logError(call *Call, correlationId string, err error) {
+			log.Printf("%%v failed, correlation id %%v: %%v", call.Method, correlationId, err)
+		}
+
+		func NewExecutor(handler HandlerInterface, options ...ExecutorOption) *Executor {
+			e := &Executor{
 				handler: handler,
+				reportError: logError,
 			}
+
+			for _, option := range options {
+				option(e)
+			}
+
+			return e
 		}
 		
-		func (e *Executor) Execute(session SessionInterface, packedMessage *[]byte) (*[]byte, error) {
+		// Execute runs a request or a batch of requests. It returns nil response if
+		// there is nothing to respond, i.e. for JSON-RPC 2.0 notifications.
+		func (e *Executor) Execute(%[2]v, packedMessage *[]byte) (*[]byte, error) {
 			if packedMessage == nil {
 				return nil, errors.New("message text is required")
 			}
 
-			response := e.execute(session, packedMessage)
-			packed, _ := json.Marshal(response)
+			call := func(method string, params json.RawMessage) (interface{}, *exchange.Error) {
+				return e.call(%[3]v, method, params)
+			}
+
+			var packed []byte
+			if e.jsonRpc {
+				packed = exchange.ExecuteJsonRpc(*packedMessage, e.batchConcurrency, call)
+			} else {
+				packed = exchange.Execute(*packedMessage, e.batchConcurrency, call)
+			}
+
+			if packed == nil {
+				return nil, nil
+			}
+
 			return &packed, nil
 		}
 
-		func (e *Executor) execute(session SessionInterface, packedMessage *[]byte) exchange.ResponseMessage {
-			
-			var requestMessage exchange.RequestMessage
-			err := json.Unmarshal(*packedMessage, &requestMessage)
-			if err != nil {
-				return exchange.NewErrorResponse("", "WrongRequest", "can't parse message")
+		func (e *Executor) call(%[2]v, method string, rawParams json.RawMessage) (interface{}, *exchange.Error) {
+			switch(method) {
+				%[1]v
 			}
 
-			requestId := requestMessage.Id
+			return nil, exchange.NewMethodNotFoundError(method)
+		}
+
+		func (e *Executor) invoke(call *Call, handle CallHandler) (result interface{}, callErr *exchange.Error) {
+			defer func() {
+				recovered := recover()
+				if recovered != nil {
+					result, callErr = nil, e.hideError(call, exchange.NewPanicError(recovered))
+				}
+			}()
 
-			switch(requestMessage.Method) {
-				%v
+			for index := len(e.interceptors) - 1; index >= 0; index-- {
+				handle = intercept(e.interceptors[index], handle)
 			}
 
-			return exchange.NewErrorResponse("", "WrongRequest", "no such method")
+			result, err := handle(call)
+			if err != nil {
+				if publicErr, ok := err.(*exchange.Error); ok {
+					return nil, publicErr
+				}
+
+				return nil, e.hideError(call, err)
+			}
+
+			return result, nil
+		}
+
+		func (e *Executor) hideError(call *Call, err error) *exchange.Error {
+			correlationId := exchange.NewCorrelationId()
+			e.reportError(call, correlationId, err)
+
+			return exchange.NewHiddenInternalError(correlationId)
 		}
-	`, service.Package, cases)
+
+		func intercept(interceptor Interceptor, next CallHandler) CallHandler {
+			return func(call *Call) (interface{}, error) {
+				return interceptor(call, next)
+			}
+		}
+	`, cases, sessionParams, sessionArgs, callContext)
+
+	text := fmt.Sprintf(`
+		//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
+		package %v
+
+		%v
+
+		%v
+	`, service.Package, buildImports(
+		body,
+		append([]goImport{
+			{"context", "context"},
+			{"json", "encoding/json"},
+			{"fmt", "fmt"},
+			{"log", "log"},
+			{"errors", "github.com/pkg/errors"},
+			{"time", "time"},
+			{"exchange", "github.com/akaumov/go-service/exchange"},
+		}, packageGoImports(service)...)...,
+	), body)
 
 	formattedText, err := format.Source([]byte(text))
 	if err != nil {
@@ -75,12 +202,14 @@ func buildExecutorFile(service *Service) (string, error) {
 	return string(formattedText), nil
 }
 
-func buildExecutorCase(methodName MethodName, methodData MethodData) string {
+// buildExecutorCase passes errors of the handler through declaredError if the
+// service declares errors, so those the method doesn't declare are hidden.
+func buildExecutorCase(methodName MethodName, methodData MethodData, hasErrors bool, options BuildOptions) string {
 	returnTypeInfo := methodData.Result
 	paramsName := strings.Title(string(methodName)) + "Params"
 
 	resultTypeGo := getGoType(returnTypeInfo)
-	if returnTypeInfo.IsCustomType || returnTypeInfo.IsArray {
+	if isPassedByPointer(returnTypeInfo) {
 		resultTypeGo = "*" + resultTypeGo
 	}
 
@@ -95,7 +224,7 @@ func buildExecutorCase(methodName MethodName, methodData MethodData) string {
 		}
 
 		param := "params." + strings.Title(string(paramName))
-		if paramTypeInfo.IsCustomType || paramTypeInfo.IsArray {
+		if isPassedByPointer(paramTypeInfo) {
 			param = "&" + param
 		}
 
@@ -104,25 +233,90 @@ func buildExecutorCase(methodName MethodName, methodData MethodData) string {
 
 	handlerMethod := strings.Title(string(methodName))
 
+	names := []string{}
+	for _, paramData := range methodData.Params {
+		names = append(names, strconv.Quote(string(paramData.Name)))
+	}
+
+	paramsAssertion := ""
+	if params != "" {
+		paramsAssertion = fmt.Sprintf("params := call.Params.(*%v)", paramsName)
+	}
+
+	callFields := fmt.Sprintf("Method: %q, Params: &params, Session: session", methodName)
+	handlerArgs := "call.Session, " + params
+	if options.Context {
+		callFields += ", Context: ctx"
+		handlerArgs = "call.Context, " + handlerArgs
+	}
+
+	errorNames := []string{}
+	for _, methodError := range methodData.Errors {
+		errorNames = append(errorNames, strconv.Quote(string(methodError.Name)))
+	}
+
+	returnedErr := "err"
+	if hasErrors {
+		returnedErr = fmt.Sprintf("declaredError(%v)", strings.Join(append([]string{"err"}, errorNames...), ", "))
+	}
+
+	handle := fmt.Sprintf("return e.handler.%v(%v)", handlerMethod, handlerArgs)
+	if hasErrors {
+		handle = fmt.Sprintf(`
+			result, err := e.handler.%v(%v)
+			return result, %v
+		`, handlerMethod, handlerArgs, returnedErr)
+	}
+
+	if methodData.Timeout > 0 {
+		handle = fmt.Sprintf(`
+			ctx, cancel := context.WithTimeout(call.Context, %v)
+			defer cancel()
+
+			result, err := e.handler.%v(ctx, call.Session, %v)
+			if err != nil && ctx.Err() == context.DeadlineExceeded {
+				return nil, exchange.NewTimeoutError(%q)
+			}
+
+			return result, %v
+		`, getGoDuration(methodData.Timeout), handlerMethod, params, fmt.Sprintf("%v timed out after %v", methodName, methodData.Timeout), returnedErr)
+	}
+
+	call := fmt.Sprintf(`
+		return e.invoke(&Call{%v}, func(call *Call) (interface{}, error) {
+			%v
+			%v
+		})
+	`, callFields, paramsAssertion, handle)
+
 	return fmt.Sprintf(`
 		case "%v":
 			var params %v
-			err := json.Unmarshal(requestMessage.Params, &params)
+			err := exchange.UnmarshalParams(rawParams, &params, %v)
 			if err != nil {
-				return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't parse params: %%v", err))
+				return nil, exchange.NewInvalidParamsError(fmt.Sprintf("can't parse params: %%v", err), nil)
 			}
 
 			err = params.Validate() 
 			if err != nil {
-				return exchange.NewErrorResponse(requestId, "WrongRequest", fmt.Sprintf("can't wrong params: %%v", err))
+				return nil, exchange.NewInvalidParamsError(fmt.Sprintf("invalid params: %%v", err), err)
 			}
 
-			result, err := e.handler.%v(session, %v)
-			if err != nil {
-				return exchange.NewErrorResponse(requestId, "ServerError", err.Error())
-			}
-			
-			return exchange.NewResultResponse(requestId, result)
+			%v
+
+	`, methodName, paramsName, strings.Join(names, ", "), call)
+}
+func getGoDuration(duration time.Duration) string {
+	switch {
+	case duration%time.Hour == 0:
+		return fmt.Sprintf("%v * time.Hour", int64(duration/time.Hour))
+	case duration%time.Minute == 0:
+		return fmt.Sprintf("%v * time.Minute", int64(duration/time.Minute))
+	case duration%time.Second == 0:
+		return fmt.Sprintf("%v * time.Second", int64(duration/time.Second))
+	case duration%time.Millisecond == 0:
+		return fmt.Sprintf("%v * time.Millisecond", int64(duration/time.Millisecond))
+	}
 
-	`, methodName, paramsName, handlerMethod, params)
+	return fmt.Sprintf("time.Duration(%v)", int64(duration))
 }
@@ -6,20 +6,27 @@ import (
 	"strings"
 )
 
-func buildHandlerInterfaceFile(service *Service) (string, error) {
+func buildHandlerInterfaceFile(service *Service, options BuildOptions) (string, error) {
 	methods := ""
-	for method, methodData := range service.Methods {
-		methods += buildHandlerMethod(method, methodData) + "\n"
+	for _, method := range service.Methods {
+		methods += getDocComment(method.Documentation) + buildHandlerMethod(method.Name, method.MethodData, options) + "\n"
 	}
 
 	text := fmt.Sprintf(`
 		//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
 		package %v
 
+		%v
+
 		type HandlerInterface interface {
 			%v
 		}
-	`, service.Package, methods)
+	`, service.Package, buildImports(
+		methods,
+		append([]goImport{
+			{"context", "context"},
+		}, packageGoImports(service)...)...,
+	), methods)
 
 	formattedText, err := format.Source([]byte(text))
 	if err != nil {
@@ -29,17 +36,18 @@ func buildHandlerInterfaceFile(service *Service) (string, error) {
 	return string(formattedText), nil
 }
 
-func buildHandlerMethod(methodName MethodName, methodData MethodData) string {
+func buildHandlerMethod(methodName MethodName, methodData MethodData, options BuildOptions) string {
 	returnTypeInfo := methodData.Result
 
 	resultTypeGo := getGoType(returnTypeInfo)
-	if returnTypeInfo.IsCustomType || returnTypeInfo.IsArray {
+	if isPassedByPointer(returnTypeInfo) {
 		resultTypeGo = "*" + resultTypeGo
 	}
 
 	params := ""
-	for _, paramData := range methodData.Params {
-		paramName := paramData.Name
+	argumentNames := getArgumentNames(methodData.Params, "ctx", "session")
+	for index, paramData := range methodData.Params {
+		paramName := argumentNames[index]
 		paramTypeInfo := paramData.TypeInfo
 
 		if params != "" {
@@ -47,7 +55,7 @@ func buildHandlerMethod(methodName MethodName, methodData MethodData) string {
 		}
 
 		paramTypeGo := getGoType(paramTypeInfo)
-		if paramTypeInfo.IsCustomType || paramTypeInfo.IsArray {
+		if isPassedByPointer(paramTypeInfo) {
 			paramTypeGo = "*" + paramTypeGo
 		}
 
@@ -55,5 +63,9 @@ func buildHandlerMethod(methodName MethodName, methodData MethodData) string {
 	}
 
 	name := strings.Title(string(methodName))
+	if options.Context {
+		return fmt.Sprintf("%v(ctx context.Context, session SessionInterface, %v) (%v, error)", name, params, resultTypeGo)
+	}
+
 	return fmt.Sprintf("%v(session SessionInterface, %v) (%v, error)", name, params, resultTypeGo)
 }
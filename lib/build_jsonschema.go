@@ -0,0 +1,331 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// jsonSchema is the subset of JSON Schema generated from the schema types.
+type jsonSchema struct {
+	Schema           string                   `json:"$schema,omitempty"`
+	Id               string                   `json:"$id,omitempty"`
+	Ref              string                   `json:"$ref,omitempty"`
+	Title            string                   `json:"title,omitempty"`
+	Description      string                   `json:"description,omitempty"`
+	Type             interface{}              `json:"type,omitempty"`
+	Format           string                   `json:"format,omitempty"`
+	Pattern          string                   `json:"pattern,omitempty"`
+	MinLength        *int                     `json:"minLength,omitempty"`
+	MaxLength        *int                     `json:"maxLength,omitempty"`
+	Minimum          *int64                   `json:"minimum,omitempty"`
+	ExclusiveMinimum *int64                   `json:"exclusiveMinimum,omitempty"`
+	Maximum          *int64                   `json:"maximum,omitempty"`
+	ExclusiveMaximum *int64                   `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       int64                    `json:"multipleOf,omitempty"`
+	Const            interface{}              `json:"const,omitempty"`
+	Enum             []interface{}            `json:"enum,omitempty"`
+	Items            *jsonSchema              `json:"items,omitempty"`
+	MinItems         *int                     `json:"minItems,omitempty"`
+	MaxItems         *int                     `json:"maxItems,omitempty"`
+	Properties       jsonSchemaProperties     `json:"properties,omitempty"`
+	PropertyNames    *jsonSchema              `json:"propertyNames,omitempty"`
+	Additional       *jsonSchema              `json:"additionalProperties,omitempty"`
+	MinProperties    *int                     `json:"minProperties,omitempty"`
+	MaxProperties    *int                     `json:"maxProperties,omitempty"`
+	Required         []string                 `json:"required,omitempty"`
+	AllOf            []*jsonSchema            `json:"allOf,omitempty"`
+	AnyOf            []*jsonSchema            `json:"anyOf,omitempty"`
+	OneOf            []*jsonSchema            `json:"oneOf,omitempty"`
+	Discriminator    *jsonSchemaDiscriminator `json:"discriminator,omitempty"`
+	Defs             jsonSchemaProperties     `json:"$defs,omitempty"`
+}
+
+type jsonSchemaDiscriminator struct {
+	PropertyName string `json:"propertyName"`
+}
+
+type jsonSchemaProperty struct {
+	Name   string
+	Schema *jsonSchema
+}
+
+// jsonSchemaProperties keeps the order of the schema fields in the JSON.
+type jsonSchemaProperties []jsonSchemaProperty
+
+func (p jsonSchemaProperties) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+	for index, property := range p {
+		if index > 0 {
+			buffer.WriteString(",")
+		}
+
+		packedSchema, err := json.Marshal(property.Schema)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.WriteString(strconv.Quote(property.Name))
+		buffer.WriteString(":")
+		buffer.Write(packedSchema)
+	}
+	buffer.WriteString("}")
+
+	return buffer.Bytes(), nil
+}
+
+var jsonSchemaFormats = map[string]string{
+	"uuid":     "uuid",
+	"email":    "email",
+	"url":      "uri",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+	"date":     "date",
+}
+
+var jsonSchemaPatterns = map[string]string{
+	"phone":  phonePattern,
+	"hex":    "^[0-9a-fA-F]+$",
+	"base64": "^[A-Za-z0-9+/]*={0,2}$",
+}
+
+var jsonSchemaTypes = map[string]string{
+	"int":    "integer",
+	"int64":  "integer",
+	"bool":   "boolean",
+	"string": "string",
+}
+
+// jsonSchemaBuilder converts schema types to JSON Schema, referencing custom
+// types by ref(name).
+type jsonSchemaBuilder struct {
+	service *Service
+	ref     func(name string) string
+}
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+func (b *jsonSchemaBuilder) typeInfoSchema(typeInfo TypeInfo) *jsonSchema {
+	var schema *jsonSchema
+
+	switch {
+	case typeInfo.IsArray:
+		itemTypeInfo := typeInfo
+		itemTypeInfo.IsArray = false
+		itemTypeInfo.IsOptional = false
+		itemTypeInfo.Min = 0
+		itemTypeInfo.Max = 0
+
+		schema = &jsonSchema{Type: "array", Items: b.typeInfoSchema(itemTypeInfo)}
+		if typeInfo.Min > 0 {
+			schema.MinItems = intPointer(typeInfo.Min)
+		}
+		if typeInfo.Max > 0 {
+			schema.MaxItems = intPointer(typeInfo.Max)
+		}
+
+	case typeInfo.IsMap:
+		itemTypeInfo := typeInfo
+		itemTypeInfo.IsMap = false
+		itemTypeInfo.IsOptional = false
+		itemTypeInfo.Min = 0
+		itemTypeInfo.Max = 0
+
+		schema = &jsonSchema{Type: "object", Additional: b.typeInfoSchema(itemTypeInfo)}
+		if format, ok := jsonSchemaFormats[typeInfo.KeyType]; ok {
+			schema.PropertyNames = &jsonSchema{Format: format}
+		} else if mapKeyGoTypes[typeInfo.KeyType] != "string" {
+			schema.PropertyNames = &jsonSchema{Pattern: "^-?[0-9]+$"}
+		}
+		if typeInfo.Min > 0 {
+			schema.MinProperties = intPointer(typeInfo.Min)
+		}
+		if typeInfo.Max > 0 {
+			schema.MaxProperties = intPointer(typeInfo.Max)
+		}
+
+	case typeInfo.IsCustomType:
+		schema = &jsonSchema{Ref: b.ref(typeInfo.DataType)}
+
+	default:
+		schema = b.simpleSchema(typeInfo)
+	}
+
+	if !typeInfo.IsOptional {
+		return schema
+	}
+
+	if typeName, ok := schema.Type.(string); ok && schema.Ref == "" {
+		schema.Type = []string{typeName, "null"}
+		return schema
+	}
+
+	return &jsonSchema{AnyOf: []*jsonSchema{schema, {Type: "null"}}}
+}
+
+func (b *jsonSchemaBuilder) simpleSchema(typeInfo TypeInfo) *jsonSchema {
+	schema := &jsonSchema{
+		Type:       jsonSchemaTypes[builtinGoTypes[typeInfo.DataType]],
+		Format:     jsonSchemaFormats[typeInfo.DataType],
+		Pattern:    jsonSchemaPatterns[typeInfo.DataType],
+		MultipleOf: typeInfo.MultipleOf,
+	}
+
+	if builtinGoTypes[typeInfo.DataType] == "int64" {
+		schema.Format = "int64"
+	}
+
+	if typeInfo.Pattern != "" {
+		if schema.Pattern != "" {
+			schema.AllOf = []*jsonSchema{{Pattern: typeInfo.Pattern}}
+		} else {
+			schema.Pattern = typeInfo.Pattern
+		}
+	}
+
+	if schema.Type == "string" {
+		if typeInfo.Min > 0 {
+			schema.MinLength = intPointer(typeInfo.Min)
+		}
+		if typeInfo.Max > 0 {
+			schema.MaxLength = intPointer(typeInfo.Max)
+		}
+	}
+
+	if minimum := typeInfo.Minimum; minimum != nil {
+		if minimum.Exclusive {
+			schema.ExclusiveMinimum = int64Pointer(minimum.Value)
+		} else {
+			schema.Minimum = int64Pointer(minimum.Value)
+		}
+	}
+
+	if maximum := typeInfo.Maximum; maximum != nil {
+		if maximum.Exclusive {
+			schema.ExclusiveMaximum = int64Pointer(maximum.Value)
+		} else {
+			schema.Maximum = int64Pointer(maximum.Value)
+		}
+	}
+
+	return schema
+}
+
+func (b *jsonSchemaBuilder) enumSchema(data EnumTypeData) *jsonSchema {
+	schema := &jsonSchema{Type: jsonSchemaTypes[data.Type], Enum: []interface{}{}}
+	for _, value := range data.Values {
+		if data.Type == "int" {
+			schema.Enum = append(schema.Enum, value.IntegerValue)
+		} else {
+			schema.Enum = append(schema.Enum, value.StringValue)
+		}
+	}
+
+	return schema
+}
+
+// structSchema describes variable fields by a oneOf of the objects selected
+// by the values of their mapField.
+func (b *jsonSchemaBuilder) structSchema(data StructTypeData) *jsonSchema {
+	schema := &jsonSchema{Type: "object", Properties: jsonSchemaProperties{}}
+	variants := []*jsonSchema{}
+
+	for _, field := range data {
+		if !field.TypeInfo.IsOptional {
+			schema.Required = append(schema.Required, string(field.Name))
+		}
+
+		if !field.TypeInfo.IsVariable {
+			schema.Properties = append(schema.Properties, jsonSchemaProperty{Name: string(field.Name), Schema: b.typeInfoSchema(field.TypeInfo)})
+			continue
+		}
+
+		mapField, _ := findField(data, field.TypeInfo.MapField)
+		variant := &jsonSchema{Discriminator: &jsonSchemaDiscriminator{PropertyName: string(mapField.Name)}}
+
+		for _, mappingCase := range field.TypeInfo.Mapping {
+			variant.OneOf = append(variant.OneOf, &jsonSchema{
+				Properties: jsonSchemaProperties{
+					{Name: string(mapField.Name), Schema: &jsonSchema{Const: discriminatorValue(b.service, mapField.TypeInfo, mappingCase)}},
+					{Name: string(field.Name), Schema: b.typeInfoSchema(mappingCase.TypeInfo)},
+				},
+			})
+		}
+
+		variants = append(variants, variant)
+	}
+
+	switch len(variants) {
+	case 0:
+	case 1:
+		schema.OneOf = variants[0].OneOf
+		schema.Discriminator = variants[0].Discriminator
+	default:
+		schema.AllOf = variants
+	}
+
+	return schema
+}
+
+// typeSchemas returns the schemas of the types of service and its imports.
+func (b *jsonSchemaBuilder) typeSchemas() (jsonSchemaProperties, error) {
+	types, err := allTypes(b.service)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := jsonSchemaProperties{}
+	for _, serviceType := range types {
+		typeBuilder := &jsonSchemaBuilder{service: serviceType.Service, ref: b.ref}
+
+		switch typeData := serviceType.Data.(type) {
+		case StructTypeData:
+			schemas = append(schemas, jsonSchemaProperty{Name: string(serviceType.Name), Schema: typeBuilder.structSchema(typeData)})
+		case EnumTypeData:
+			schemas = append(schemas, jsonSchemaProperty{Name: string(serviceType.Name), Schema: typeBuilder.enumSchema(typeData)})
+		}
+	}
+
+	return schemas, nil
+}
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+func getJsonSchemaFileName(name string) string {
+	return name + ".schema.json"
+}
+
+// buildJsonSchemaFiles returns a JSON Schema document per type of service and
+// its imports by file name. Documents reference each other by file name.
+func buildJsonSchemaFiles(service *Service) (map[string]string, error) {
+	builder := &jsonSchemaBuilder{service: service, ref: getJsonSchemaFileName}
+
+	schemas, err := builder.typeSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]string{}
+	for _, property := range schemas {
+		schema := property.Schema
+		schema.Schema = jsonSchemaDraft
+		schema.Id = getJsonSchemaFileName(property.Name)
+		schema.Title = property.Name
+
+		packedSchema, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		files[getJsonSchemaFileName(property.Name)] = string(packedSchema) + "\n"
+	}
+
+	return files, nil
+}
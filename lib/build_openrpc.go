@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const openRpcVersion = "1.2.6"
+
+type openRpcDocument struct {
+	OpenRpc    string            `json:"openrpc"`
+	Info       openRpcInfo       `json:"info"`
+	Methods    []openRpcMethod   `json:"methods"`
+	Components openRpcComponents `json:"components"`
+}
+
+type openRpcInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openRpcMethod struct {
+	Name           string            `json:"name"`
+	Description    string            `json:"description,omitempty"`
+	Deprecated     bool              `json:"deprecated,omitempty"`
+	ParamStructure string            `json:"paramStructure"`
+	Params         []openRpcContent  `json:"params"`
+	Result         openRpcContent    `json:"result"`
+	Errors         []openRpcErrorRef `json:"errors,omitempty"`
+}
+
+type openRpcContent struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Deprecated  bool        `json:"deprecated,omitempty"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+type openRpcErrorRef struct {
+	Ref string `json:"$ref"`
+}
+
+type openRpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    *jsonSchema `json:"data,omitempty"`
+}
+
+type openRpcComponents struct {
+	Schemas jsonSchemaProperties    `json:"schemas"`
+	Errors  map[string]openRpcError `json:"errors,omitempty"`
+}
+
+// buildOpenRpcFile returns the OpenRPC document of service. It describes the
+// JSON-RPC 2.0 mode of the executor, params may be passed by name or position.
+func buildOpenRpcFile(service *Service) (string, error) {
+	builder := &jsonSchemaBuilder{
+		service: service,
+		ref: func(name string) string {
+			return "#/components/schemas/" + name
+		},
+	}
+
+	schemas, err := builder.typeSchemas()
+	if err != nil {
+		return "", err
+	}
+
+	title := service.Name
+	if title == "" {
+		title = service.Package
+	}
+
+	document := openRpcDocument{
+		OpenRpc: openRpcVersion,
+		Info: openRpcInfo{
+			Title:       title,
+			Description: service.Description,
+			Version:     service.Version,
+		},
+		Methods: []openRpcMethod{},
+		Components: openRpcComponents{
+			Schemas: schemas,
+		},
+	}
+
+	if len(service.Errors) > 0 {
+		document.Components.Errors = map[string]openRpcError{}
+	}
+
+	for _, definition := range service.Errors {
+		message := definition.Message
+		if message == "" {
+			message = string(definition.Name)
+		}
+
+		openRpcError := openRpcError{
+			Code:    definition.Code,
+			Message: message,
+		}
+
+		if len(definition.Data) > 0 {
+			openRpcError.Data = builder.structSchema(definition.Data)
+		}
+
+		document.Components.Errors[string(definition.Name)] = openRpcError
+	}
+
+	for _, method := range service.Methods {
+		openRpcMethod := openRpcMethod{
+			Name:           string(method.Name),
+			Description:    getOpenRpcDescription(method.Documentation),
+			Deprecated:     method.IsDeprecated,
+			ParamStructure: "either",
+			Params:         []openRpcContent{},
+			Result: openRpcContent{
+				Name:   string(method.Name) + "Result",
+				Schema: builder.typeInfoSchema(method.Result),
+			},
+		}
+
+		for _, param := range method.Params {
+			openRpcMethod.Params = append(openRpcMethod.Params, openRpcContent{
+				Name:        string(param.Name),
+				Description: getOpenRpcDescription(param.TypeInfo.Documentation),
+				Required:    !param.TypeInfo.IsOptional,
+				Deprecated:  param.TypeInfo.IsDeprecated,
+				Schema:      builder.typeInfoSchema(param.TypeInfo),
+			})
+		}
+
+		for _, methodError := range method.Errors {
+			openRpcMethod.Errors = append(openRpcMethod.Errors, openRpcErrorRef{Ref: "#/components/errors/" + string(methodError.Name)})
+		}
+
+		document.Methods = append(document.Methods, openRpcMethod)
+	}
+
+	packedDocument, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(packedDocument) + "\n", nil
+}
+
+// getOpenRpcDescription appends the deprecation note to the description,
+// OpenRPC only has a deprecated flag.
+func getOpenRpcDescription(documentation Documentation) string {
+	description := documentation.Description
+	if documentation.Deprecation == "" {
+		return description
+	}
+
+	if description != "" {
+		description += "\n\n"
+	}
+
+	return description + "Deprecated: " + strings.TrimSpace(documentation.Deprecation)
+}
@@ -0,0 +1,342 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var protobufTypes = map[string]string{
+	"int":    "int64",
+	"int64":  "int64",
+	"bool":   "bool",
+	"string": "string",
+}
+
+// numbers 19000-19999 are reserved for the protobuf implementation
+const (
+	protobufFirstReservedNumber = 19000
+	protobufLastReservedNumber  = 19999
+)
+
+// protobufLock persists the numbers of message fields and string enum values
+// between builds, so regenerated files never renumber them. Numbers of removed
+// fields stay reserved.
+type protobufLock struct {
+	Messages map[string]*protobufLockEntry `json:"messages"`
+	Enums    map[string]*protobufLockEntry `json:"enums"`
+}
+
+type protobufLockEntry struct {
+	Numbers  map[string]int `json:"numbers"`
+	Reserved map[string]int `json:"reserved,omitempty"`
+}
+
+func newProtobufLock() *protobufLock {
+	return &protobufLock{
+		Messages: map[string]*protobufLockEntry{},
+		Enums:    map[string]*protobufLockEntry{},
+	}
+}
+
+func parseProtobufLock(packed []byte) (*protobufLock, error) {
+	lock := newProtobufLock()
+	err := json.Unmarshal(packed, lock)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse protobuf lock: %v", err)
+	}
+
+	if lock.Messages == nil {
+		lock.Messages = map[string]*protobufLockEntry{}
+	}
+
+	if lock.Enums == nil {
+		lock.Enums = map[string]*protobufLockEntry{}
+	}
+
+	return lock, nil
+}
+
+func getLockEntry(entries map[string]*protobufLockEntry, name string) *protobufLockEntry {
+	entry, ok := entries[name]
+	if !ok {
+		entry = &protobufLockEntry{}
+		entries[name] = entry
+	}
+
+	if entry.Numbers == nil {
+		entry.Numbers = map[string]int{}
+	}
+
+	if entry.Reserved == nil {
+		entry.Reserved = map[string]int{}
+	}
+
+	return entry
+}
+
+// number returns the locked number of name, a removed name gets its number back.
+func (e *protobufLockEntry) number(name string) int {
+	if number, ok := e.Numbers[name]; ok {
+		return number
+	}
+
+	if number, ok := e.Reserved[name]; ok {
+		delete(e.Reserved, name)
+		e.Numbers[name] = number
+		return number
+	}
+
+	number := 1
+	for _, numbers := range []map[string]int{e.Numbers, e.Reserved} {
+		for _, used := range numbers {
+			if used >= number {
+				number = used + 1
+			}
+		}
+	}
+
+	if number >= protobufFirstReservedNumber && number <= protobufLastReservedNumber {
+		number = protobufLastReservedNumber + 1
+	}
+
+	e.Numbers[name] = number
+	return number
+}
+
+// reserveUnused moves the names missing from used to the reserved ones and
+// returns the statements reserving them.
+func (e *protobufLockEntry) reserveUnused(used map[string]bool) string {
+	for name, number := range e.Numbers {
+		if !used[name] {
+			e.Reserved[name] = number
+			delete(e.Numbers, name)
+		}
+	}
+
+	if len(e.Reserved) == 0 {
+		return ""
+	}
+
+	names := []string{}
+	for name := range e.Reserved {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return e.Reserved[names[i]] < e.Reserved[names[j]]
+	})
+
+	numbers := []string{}
+	quotedNames := []string{}
+	for _, name := range names {
+		numbers = append(numbers, fmt.Sprint(e.Reserved[name]))
+		// names of oneof cases are locked as field.case
+		if !strings.Contains(name, ".") {
+			quotedNames = append(quotedNames, fmt.Sprintf("%q", name))
+		}
+	}
+
+	statements := fmt.Sprintf("  reserved %v;\n", strings.Join(numbers, ", "))
+	if len(quotedNames) > 0 {
+		statements += fmt.Sprintf("  reserved %v;\n", strings.Join(quotedNames, ", "))
+	}
+
+	return statements
+}
+
+var protobufWordBoundaryRegexp = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+func getProtobufConstantName(name string) string {
+	return strings.ToUpper(protobufWordBoundaryRegexp.ReplaceAllString(nonIdentifierRegexp.ReplaceAllString(name, "_"), "${1}_${2}"))
+}
+
+// getProtobufEnumValueName prefixes the value name by the enum name, enum
+// values share the scope of the package.
+func getProtobufEnumValueName(prefix string, name string) string {
+	name = getProtobufConstantName(name)
+	if strings.HasPrefix(name, prefix) {
+		return name
+	}
+
+	return prefix + name
+}
+
+type protobufBuilder struct {
+	service *Service
+	lock    *protobufLock
+	text    string
+}
+
+// buildProtobufFile returns the proto3 file of service. lock is updated with
+// the numbers of new fields and the removed ones.
+func buildProtobufFile(service *Service, lock *protobufLock) (string, error) {
+	types, err := allTypes(service)
+	if err != nil {
+		return "", err
+	}
+
+	builder := &protobufBuilder{service: service, lock: lock}
+	builder.text = fmt.Sprintf(`//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
+syntax = "proto3";
+
+package %v;
+`, service.Package)
+
+	for _, serviceType := range types {
+		typeBuilder := &protobufBuilder{service: serviceType.Service, lock: lock}
+
+		switch typeData := serviceType.Data.(type) {
+		case StructTypeData:
+			err = typeBuilder.message(string(serviceType.Name), typeData)
+		case EnumTypeData:
+			typeBuilder.enum(string(serviceType.Name), typeData)
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		builder.text += typeBuilder.text
+	}
+
+	rpcs := ""
+	for _, method := range service.Methods {
+		methodName := strings.Title(string(method.Name))
+
+		fields := StructTypeData{}
+		for _, param := range method.Params {
+			fields = append(fields, Field{Name: FieldName(param.Name), TypeInfo: param.TypeInfo})
+		}
+
+		err = builder.message(methodName+"Params", fields)
+		if err != nil {
+			return "", err
+		}
+
+		resultType := method.Result.DataType
+		if !builder.isMessage(method.Result) {
+			resultType = methodName + "Result"
+
+			err = builder.message(resultType, StructTypeData{{Name: "value", TypeInfo: method.Result}})
+			if err != nil {
+				return "", err
+			}
+		}
+
+		rpcs += fmt.Sprintf("  rpc %v(%vParams) returns (%v);\n", methodName, methodName, resultType)
+	}
+
+	serviceName := service.Name
+	if serviceName == "" {
+		serviceName = service.Package
+	}
+
+	builder.text += fmt.Sprintf("\nservice %v {\n%v}\n", strings.Title(serviceName), rpcs)
+
+	return builder.text, nil
+}
+
+func (b *protobufBuilder) isMessage(typeInfo TypeInfo) bool {
+	if !typeInfo.IsCustomType || typeInfo.IsArray || typeInfo.IsMap || typeInfo.IsOptional {
+		return false
+	}
+
+	typeData, _ := b.service.lookupType(typeInfo)
+	_, isStruct := typeData.(StructTypeData)
+	return isStruct
+}
+
+func (b *protobufBuilder) scalarType(typeInfo TypeInfo) string {
+	if typeInfo.IsCustomType {
+		return typeInfo.DataType
+	}
+
+	return protobufTypes[builtinGoTypes[typeInfo.DataType]]
+}
+
+func (b *protobufBuilder) fieldType(typeInfo TypeInfo) string {
+	switch {
+	case typeInfo.IsArray:
+		return "repeated " + b.scalarType(typeInfo)
+	case typeInfo.IsMap:
+		return fmt.Sprintf("map<%v, %v>", protobufTypes[mapKeyGoTypes[typeInfo.KeyType]], b.scalarType(typeInfo))
+	case typeInfo.IsOptional:
+		return "optional " + b.scalarType(typeInfo)
+	}
+
+	return b.scalarType(typeInfo)
+}
+
+func (b *protobufBuilder) message(name string, data StructTypeData) error {
+	entry := getLockEntry(b.lock.Messages, name)
+	used := map[string]bool{}
+	fields := ""
+
+	for _, field := range data {
+		fieldName := string(field.Name)
+
+		if !field.TypeInfo.IsVariable {
+			used[fieldName] = true
+			fields += fmt.Sprintf("  %v %v = %v;\n", b.fieldType(field.TypeInfo), fieldName, entry.number(fieldName))
+			continue
+		}
+
+		cases := ""
+		for _, mappingCase := range field.TypeInfo.Mapping {
+			if mappingCase.TypeInfo.IsArray || mappingCase.TypeInfo.IsMap {
+				return fmt.Errorf("mapping %q of %v.%v: arrays and maps can't be oneof cases", mappingCase.Value, name, fieldName)
+			}
+
+			caseName := fieldName + "_" + enumValueName(mappingCase.Value, "string")
+			lockName := fieldName + "." + mappingCase.Value
+			used[lockName] = true
+
+			cases += fmt.Sprintf("    %v %v = %v;\n", b.scalarType(mappingCase.TypeInfo), caseName, entry.number(lockName))
+		}
+
+		fields += fmt.Sprintf("  oneof %v {\n%v  }\n", fieldName, cases)
+	}
+
+	b.text += fmt.Sprintf("\nmessage %v {\n%v%v}\n", name, entry.reserveUnused(used), fields)
+	return nil
+}
+
+// enum starts with an UNSPECIFIED zero value, as proto3 requires, unless an
+// int enum has a value of 0. String enums get locked numbers, int enums use
+// their values.
+func (b *protobufBuilder) enum(name string, data EnumTypeData) {
+	prefix := getProtobufConstantName(name) + "_"
+
+	values := ""
+	hasZero := false
+	if data.Type == "int" {
+		for _, value := range data.Values {
+			line := fmt.Sprintf("  %v = %v;\n", getProtobufEnumValueName(prefix, value.Name), value.IntegerValue)
+
+			// the zero value must be the first one
+			if value.IntegerValue == 0 {
+				hasZero = true
+				values = line + values
+			} else {
+				values += line
+			}
+		}
+	} else {
+		entry := getLockEntry(b.lock.Enums, name)
+		used := map[string]bool{}
+
+		for _, value := range data.Values {
+			used[value.StringValue] = true
+			values += fmt.Sprintf("  %v = %v;\n", getProtobufEnumValueName(prefix, value.Name), entry.number(value.StringValue))
+		}
+		values = entry.reserveUnused(used) + values
+	}
+
+	if !hasZero {
+		values = fmt.Sprintf("  %vUNSPECIFIED = 0;\n", prefix) + values
+	}
+
+	b.text += fmt.Sprintf("\nenum %v {\n%v}\n", name, values)
+}
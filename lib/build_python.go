@@ -0,0 +1,810 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var pythonTypes = map[string]string{
+	"int":    "int",
+	"int64":  "int",
+	"bool":   "bool",
+	"string": "str",
+}
+
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true,
+	"def": true, "del": true, "elif": true, "else": true, "except": true,
+	"finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true,
+	"not": true, "or": true, "pass": true, "raise": true, "return": true,
+	"try": true, "while": true, "with": true, "yield": true,
+}
+
+// emailPythonPattern is the email pattern of govalidator in Python syntax.
+const emailPythonPattern = "(((([a-zA-Z]|[0-9]|[!#\\$%&'\\*\\+\\-\\/=\\?\\^_`{\\|}~]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])+(\\.([a-zA-Z]|[0-9]|[!#\\$%&'\\*\\+\\-\\/=\\?\\^_`{\\|}~]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])+)*)|((\\x22)((((\\x20|\\x09)*(\\x0d\\x0a))?(\\x20|\\x09)+)?(([\\x01-\\x08\\x0b\\x0c\\x0e-\\x1f\\x7f]|\\x21|[\\x23-\\x5b]|[\\x5d-\\x7e]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])|(\\([\\x01-\\x09\\x0b\\x0c\\x0d-\\x7f]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF]))))*(((\\x20|\\x09)*(\\x0d\\x0a))?(\\x20|\\x09)+)?(\\x22)))@((([a-zA-Z]|[0-9]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])|(([a-zA-Z]|[0-9]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])([a-zA-Z]|[0-9]|-|\\.|_|~|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])*([a-zA-Z]|[0-9]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])))\\.)+(([a-zA-Z]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])|(([a-zA-Z]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])([a-zA-Z]|[0-9]|-|_|~|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])*([a-zA-Z]|[\\u00A0-\\uD7FF\\uF900-\\uFDCF\\uFDF0-\\uFFEF])))\\.?"
+
+// pythonRuntime is the part of the generated module shared by every schema:
+// validation errors, the checks of the Go validators and JSON conversion.
+const pythonRuntime = `from __future__ import annotations
+
+import dataclasses
+import datetime
+import enum
+import ipaddress
+import itertools
+import json
+import re
+import typing
+import urllib.error
+import urllib.parse
+import urllib.request
+
+
+@dataclasses.dataclass
+class ValidationError:
+    path: str
+    rule: str
+    message: str
+    params: typing.Optional[typing.Dict[str, typing.Any]] = None
+
+    def __str__(self) -> str:
+        if self.path == "":
+            return self.message
+
+        return "%s %s" % (self.path, self.message)
+
+
+class ValidationErrors(Exception):
+    def __init__(self, errors: typing.Optional[typing.List[ValidationError]] = None):
+        super().__init__()
+        self.errors = errors if errors is not None else []
+
+    def add(self, path: str, rule: str, message: str, params: typing.Optional[typing.Dict[str, typing.Any]] = None):
+        self.errors.append(ValidationError(path, rule, message, params))
+
+    def __str__(self) -> str:
+        return "; ".join(str(error) for error in self.errors)
+
+
+def join_path(path: str, name: str) -> str:
+    if path == "":
+        return name
+
+    return path + "." + name
+
+
+def index_path(path: str, index: int) -> str:
+    return "%s[%d]" % (path, index)
+
+
+_PLAIN_KEY = re.compile(r"[A-Za-z_][A-Za-z0-9_]*")
+
+
+def key_path(path: str, key: typing.Any) -> str:
+    key = str(key)
+    if _PLAIN_KEY.fullmatch(key):
+        return join_path(path, key)
+
+    return "%s[%s]" % (path, json.dumps(key, ensure_ascii=False))
+
+
+class RpcError(Exception):
+    """An error response of the service."""
+
+    def __init__(self, name: str, message: str, data: typing.Any = None):
+        super().__init__(message)
+        self.name = name
+        self.message = message
+        self.data = data
+
+
+_UUID = re.compile(r"[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}")
+_EMAIL = re.compile(r"EMAIL_PATTERN")
+_HEX = re.compile(r"[0-9a-fA-F]+")
+_BASE64 = re.compile(r"(?:[A-Za-z0-9+/]{4})*(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=|[A-Za-z0-9+/]{4})")
+_DNS_NAME = re.compile(r"([a-zA-Z0-9_][a-zA-Z0-9_-]{0,62})(\.[a-zA-Z0-9_][a-zA-Z0-9_-]{0,62})*[._]?")
+_DATE = re.compile(r"[0-9]{4}-[0-9]{2}-[0-9]{2}")
+_PHONE = re.compile(r"PHONE_PATTERN")
+
+
+def _is_ip(value: str) -> bool:
+    try:
+        ipaddress.ip_address(value)
+    except ValueError:
+        return False
+
+    return True
+
+
+def _is_url(value: str) -> bool:
+    if value == "" or len(value) >= 2083 or len(value) <= 3 or value.startswith("."):
+        return False
+
+    candidate = value
+    if ":" in value and "://" not in value:
+        candidate = "http://" + value
+
+    try:
+        parsed = urllib.parse.urlsplit(candidate)
+    except ValueError:
+        return False
+
+    if parsed.netloc.startswith("."):
+        return False
+
+    return parsed.netloc != "" or parsed.path == "" or "." in parsed.path
+
+
+def _is_hostname(value: str) -> bool:
+    if value == "" or len(value.replace(".", "")) > 255:
+        return False
+
+    return not _is_ip(value) and _DNS_NAME.fullmatch(value) is not None
+
+
+def _is_date(value: str) -> bool:
+    if _DATE.fullmatch(value) is None:
+        return False
+
+    try:
+        datetime.datetime.strptime(value, "%Y-%m-%d")
+    except ValueError:
+        return False
+
+    return True
+
+
+_FORMAT_CHECKS = {
+    "uuid": lambda value: _UUID.fullmatch(value) is not None,
+    "email": lambda value: _EMAIL.fullmatch(value) is not None,
+    "url": _is_url,
+    "ipv4": lambda value: _is_ip(value) and "." in value,
+    "ipv6": lambda value: _is_ip(value) and ":" in value,
+    "hostname": _is_hostname,
+    "date": _is_date,
+    "phone": lambda value: _PHONE.fullmatch(value) is not None,
+    "hex": lambda value: _HEX.fullmatch(value) is not None,
+    "base64": lambda value: _BASE64.fullmatch(value) is not None,
+}
+
+_FORMAT_DESCRIPTIONS = {
+FORMAT_DESCRIPTIONS}
+
+
+class _Rules:
+    """Constraints of a schema type expression."""
+
+    def __init__(self, kind: str, optional: bool = False, **constraints: typing.Any):
+        self.kind = kind
+        self.optional = optional
+        self.format = constraints.get("format")
+        self.min = constraints.get("min", 0)
+        self.max = constraints.get("max", 0)
+        self.pattern = constraints.get("pattern")
+        self.minimum = constraints.get("minimum")
+        self.maximum = constraints.get("maximum")
+        self.exclusive_minimum = constraints.get("exclusive_minimum", False)
+        self.exclusive_maximum = constraints.get("exclusive_maximum", False)
+        self.multiple_of = constraints.get("multiple_of")
+        self.cls = constraints.get("cls")
+        self.item = constraints.get("item")
+        self.key_format = constraints.get("key_format")
+        self.mapping = constraints.get("mapping", [])
+
+
+def _validate(errors: ValidationErrors, path: str, value: typing.Any, rules: _Rules):
+    if value is None:
+        if not rules.optional:
+            errors.add(path, "required", "is required")
+        return
+
+    if rules.kind == "string":
+        _validate_string(errors, path, value, rules)
+    elif rules.kind == "int":
+        _validate_int(errors, path, value, rules)
+    elif rules.kind == "bool":
+        if not isinstance(value, bool):
+            errors.add(path, "type", "must be a boolean")
+    elif rules.kind == "enum":
+        try:
+            rules.cls(value)
+        except ValueError:
+            values = [member.value for member in rules.cls]
+            errors.add(path, "enum", "must be one of: " + ", ".join(str(value) for value in values), {"values": values})
+    elif rules.kind == "struct":
+        if not isinstance(value, rules.cls):
+            errors.add(path, "type", "must be %s" % rules.cls.__name__)
+        else:
+            value.validate_at(path, errors)
+    elif rules.kind == "array":
+        if not isinstance(value, list):
+            errors.add(path, "type", "must be a list")
+            return
+
+        _validate_count(errors, path, value, rules)
+        for index, item in enumerate(value):
+            _validate(errors, index_path(path, index), item, rules.item)
+    elif rules.kind == "map":
+        if not isinstance(value, dict):
+            errors.add(path, "type", "must be a dict")
+            return
+
+        _validate_count(errors, path, value, rules)
+        for key, item in value.items():
+            item_path = key_path(path, key)
+            _validate(errors, item_path, item, rules.item)
+
+            if rules.key_format is not None and not _FORMAT_CHECKS[rules.key_format](key):
+                errors.add(item_path, rules.key_format, "key must be " + _FORMAT_DESCRIPTIONS[rules.key_format])
+    elif rules.kind == "variable":
+        for case in rules.mapping:
+            if _matches(value, case):
+                _validate(errors, path, value, case)
+                return
+
+        errors.add(path, "type", "has unexpected type %s" % type(value).__name__)
+
+
+def _matches(value: typing.Any, rules: _Rules) -> bool:
+    if rules.kind in ("struct", "enum"):
+        return isinstance(value, rules.cls)
+    if rules.kind == "int":
+        return isinstance(value, int) and not isinstance(value, bool)
+
+    return isinstance(value, {"string": str, "bool": bool, "array": list, "map": dict}[rules.kind])
+
+
+def _validate_string(errors: ValidationErrors, path: str, value: typing.Any, rules: _Rules):
+    if not isinstance(value, str):
+        errors.add(path, "type", "must be a string")
+        return
+
+    if rules.format is not None and rules.format != "phone" and not _FORMAT_CHECKS[rules.format](value):
+        errors.add(path, rules.format, "must be " + _FORMAT_DESCRIPTIONS[rules.format])
+
+    # lengths are counted in bytes as Go does
+    length = len(value.encode("utf-8"))
+    if rules.min > 0 and length < rules.min:
+        errors.add(path, "minLength", "must be at least %d characters long" % rules.min, {"min": rules.min})
+    if rules.max > 0 and length > rules.max:
+        errors.add(path, "maxLength", "must be at most %d characters long" % rules.max, {"max": rules.max})
+
+    if rules.format == "phone" and not _FORMAT_CHECKS["phone"](value):
+        errors.add(path, "phone", "must be " + _FORMAT_DESCRIPTIONS["phone"])
+
+    if rules.pattern is not None and re.search(rules.pattern, value) is None:
+        errors.add(path, "pattern", "must match pattern " + rules.pattern, {"pattern": rules.pattern})
+
+
+def _validate_int(errors: ValidationErrors, path: str, value: typing.Any, rules: _Rules):
+    if not isinstance(value, int) or isinstance(value, bool):
+        errors.add(path, "type", "must be an integer")
+        return
+
+    if rules.minimum is not None:
+        if rules.exclusive_minimum and value <= rules.minimum:
+            errors.add(path, "exclusiveMinimum", "must be greater than %d" % rules.minimum, {"minimum": rules.minimum})
+        elif not rules.exclusive_minimum and value < rules.minimum:
+            errors.add(path, "minimum", "must be greater than or equal to %d" % rules.minimum, {"minimum": rules.minimum})
+
+    if rules.maximum is not None:
+        if rules.exclusive_maximum and value >= rules.maximum:
+            errors.add(path, "exclusiveMaximum", "must be less than %d" % rules.maximum, {"maximum": rules.maximum})
+        elif not rules.exclusive_maximum and value > rules.maximum:
+            errors.add(path, "maximum", "must be less than or equal to %d" % rules.maximum, {"maximum": rules.maximum})
+
+    if rules.multiple_of is not None and value % rules.multiple_of != 0:
+        errors.add(path, "multipleOf", "must be a multiple of %d" % rules.multiple_of, {"multipleOf": rules.multiple_of})
+
+
+def _validate_count(errors: ValidationErrors, path: str, value: typing.Sized, rules: _Rules):
+    if rules.min > 0 and len(value) < rules.min:
+        errors.add(path, "minItems", "must contain at least %d items" % rules.min, {"min": rules.min})
+    if rules.max > 0 and len(value) > rules.max:
+        errors.add(path, "maxItems", "must contain at most %d items" % rules.max, {"max": rules.max})
+
+
+def _encode(value: typing.Any) -> typing.Any:
+    if isinstance(value, enum.Enum):
+        return value.value
+    if isinstance(value, list):
+        return [_encode(item) for item in value]
+    if isinstance(value, dict):
+        return {str(key): _encode(item) for key, item in value.items()}
+    if hasattr(value, "to_json"):
+        return value.to_json()
+
+    return value
+
+
+def _decode(value: typing.Any, decoder: typing.Callable[[typing.Any], typing.Any]) -> typing.Any:
+    if value is None:
+        return None
+
+    return decoder(value)
+
+
+def _same(value: typing.Any) -> typing.Any:
+    return value
+
+
+def _list_of(decoder: typing.Callable[[typing.Any], typing.Any]) -> typing.Callable[[typing.Any], typing.Any]:
+    return lambda value: [_decode(item, decoder) for item in value]
+
+
+def _dict_of(key: typing.Callable[[str], typing.Any], decoder: typing.Callable[[typing.Any], typing.Any]) -> typing.Callable[[typing.Any], typing.Any]:
+    return lambda value: {key(name): _decode(item, decoder) for name, item in value.items()}
+
+
+def _one_of(discriminator: typing.Any, decoders: typing.Dict[typing.Any, typing.Callable[[typing.Any], typing.Any]]) -> typing.Callable[[typing.Any], typing.Any]:
+    def decode(value: typing.Any) -> typing.Any:
+        if discriminator not in decoders:
+            raise ValueError("unknown variant: %r" % (discriminator,))
+
+        return decoders[discriminator](value)
+
+    return decode
+`
+
+// pythonClient is the part of the generated module sending requests in the
+// envelope of exchange.RequestMessage.
+const pythonClient = `
+
+def _decode_error(error: typing.Dict[str, typing.Any]) -> RpcError:
+    name = error.get("name", "")
+    message = error.get("message", "")
+    data = error.get("data")
+
+    declared = _DECLARED_ERRORS.get(name)
+    if declared is not None:
+        return declared.from_json(message, data)
+
+    return RpcError(name, message, data)
+
+
+class HttpTransport:
+    """Posts requests to the URL of a service served with httptransport."""
+
+    def __init__(self, url: str, headers: typing.Optional[typing.Dict[str, str]] = None, timeout: typing.Optional[float] = None):
+        self.url = url
+        self.headers = dict(headers or {})
+        self.timeout = timeout
+
+    def __call__(self, request: bytes) -> bytes:
+        headers = dict(self.headers)
+        headers["Content-Type"] = "application/json"
+
+        http_request = urllib.request.Request(self.url, data=request, headers=headers, method="POST")
+        try:
+            with urllib.request.urlopen(http_request, timeout=self.timeout) as response:
+                return response.read()
+        except urllib.error.HTTPError as error:
+            # error responses of the service still carry the envelope
+            if error.headers.get_content_type() == "application/json":
+                return error.read()
+            raise
+
+
+class Client:
+    """Calls the methods of the service through transport, a callable sending
+    a packed request and returning the packed response."""
+
+    def __init__(self, transport: typing.Callable[[bytes], bytes]):
+        self._transport = transport
+        self._ids = itertools.count(1)
+
+    def _call(self, method: str, params: typing.Any, decoder: typing.Callable[[typing.Any], typing.Any]) -> typing.Any:
+        params.validate()
+
+        request_id = str(next(self._ids))
+        request = json.dumps({"id": request_id, "method": method, "params": params.to_json()})
+
+        response = json.loads(self._transport(request.encode("utf-8")))
+
+        error = response.get("error")
+        if error is not None:
+            raise _decode_error(error)
+
+        if response.get("id") != request_id:
+            raise RpcError("WrongResponse", "response id %r doesn't match request id %r" % (response.get("id"), request_id))
+
+        return _decode(response.get("result"), decoder)
+`
+
+var pythonWordBoundaryRegexp = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+func getPythonName(name string) string {
+	if pythonKeywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+func getPythonMethodName(name MethodName) string {
+	return getPythonName(strings.ToLower(pythonWordBoundaryRegexp.ReplaceAllString(string(name), "${1}_${2}")))
+}
+
+func buildPythonFile(service *Service) (string, error) {
+	types, err := allTypes(service)
+	if err != nil {
+		return "", err
+	}
+
+	descriptions := []string{}
+	for format, description := range formatDescriptions {
+		descriptions = append(descriptions, fmt.Sprintf("    %q: %q,\n", format, description))
+	}
+	sort.Strings(descriptions)
+
+	runtime := strings.NewReplacer(
+		"EMAIL_PATTERN", emailPythonPattern,
+		"PHONE_PATTERN", strings.TrimSuffix(strings.TrimPrefix(phonePattern, "^"), "$"),
+		"\nFORMAT_DESCRIPTIONS}", "\n"+strings.Join(descriptions, "")+"}",
+	).Replace(pythonRuntime)
+
+	text := "#!!!GENERATED BY \"GO-SERVICE\" DON'T CHANGE THIS FILE!!!\n" + runtime
+
+	for _, serviceType := range types {
+		switch typeData := serviceType.Data.(type) {
+		case StructTypeData:
+			fields := []Parameter{}
+			for _, field := range typeData {
+				fields = append(fields, Parameter{Name: ParamName(field.Name), TypeInfo: field.TypeInfo})
+			}
+
+			text += buildPythonDataclass(serviceType.Service, string(serviceType.Name), fields)
+		case EnumTypeData:
+			text += buildPythonEnum(serviceType.Name, typeData)
+		}
+	}
+
+	for _, method := range service.Methods {
+		text += buildPythonDataclass(service, strings.Title(string(method.Name))+"Params", method.Params)
+	}
+
+	text += buildPythonErrors(service)
+	text += pythonClient
+
+	for _, method := range service.Methods {
+		text += buildPythonMethod(service, method)
+	}
+
+	return text, nil
+}
+
+func getPythonType(typeInfo TypeInfo) string {
+	resultType := ""
+	switch {
+	case typeInfo.IsVariable:
+		cases := []string{}
+		for _, mappingCase := range typeInfo.Mapping {
+			cases = append(cases, getPythonType(mappingCase.TypeInfo))
+		}
+		resultType = fmt.Sprintf("typing.Union[%v]", strings.Join(cases, ", "))
+	case typeInfo.IsCustomType:
+		resultType = typeInfo.DataType
+	default:
+		resultType = pythonTypes[builtinGoTypes[typeInfo.DataType]]
+	}
+
+	if typeInfo.IsArray {
+		resultType = fmt.Sprintf("typing.List[%v]", resultType)
+	}
+
+	if typeInfo.IsMap {
+		resultType = fmt.Sprintf("typing.Dict[%v, %v]", pythonTypes[mapKeyGoTypes[typeInfo.KeyType]], resultType)
+	}
+
+	if typeInfo.IsOptional {
+		resultType = fmt.Sprintf("typing.Optional[%v]", resultType)
+	}
+
+	return resultType
+}
+
+// getPythonRules returns the _Rules expression of the constraints of
+// typeInfo, checked the way the generated Go Validate() checks them.
+func getPythonRules(service *Service, typeInfo TypeInfo) string {
+	arguments := []string{}
+	if typeInfo.IsOptional {
+		arguments = append(arguments, "optional=True")
+	}
+
+	if typeInfo.IsArray || typeInfo.IsMap {
+		itemTypeInfo := typeInfo
+		itemTypeInfo.IsArray = false
+		itemTypeInfo.IsMap = false
+		itemTypeInfo.IsOptional = false
+		itemTypeInfo.Min = 0
+		itemTypeInfo.Max = 0
+
+		arguments = append(arguments, "item="+getPythonRules(service, itemTypeInfo))
+		if typeInfo.Min > 0 {
+			arguments = append(arguments, fmt.Sprintf("min=%v", typeInfo.Min))
+		}
+		if typeInfo.Max > 0 {
+			arguments = append(arguments, fmt.Sprintf("max=%v", typeInfo.Max))
+		}
+
+		if !typeInfo.IsMap {
+			return fmt.Sprintf("_Rules(%v)", strings.Join(append([]string{`"array"`}, arguments...), ", "))
+		}
+
+		if _, ok := formatValidators[typeInfo.KeyType]; ok {
+			arguments = append(arguments, fmt.Sprintf("key_format=%q", typeInfo.KeyType))
+		}
+
+		return fmt.Sprintf("_Rules(%v)", strings.Join(append([]string{`"map"`}, arguments...), ", "))
+	}
+
+	kind := ""
+	switch {
+	case typeInfo.IsVariable:
+		kind = "variable"
+
+		cases := []string{}
+		for _, mappingCase := range typeInfo.Mapping {
+			cases = append(cases, getPythonRules(service, mappingCase.TypeInfo))
+		}
+		arguments = append(arguments, fmt.Sprintf("mapping=[%v]", strings.Join(cases, ", ")))
+
+	case typeInfo.IsCustomType:
+		kind = "struct"
+		if typeData, ok := service.lookupType(typeInfo); ok {
+			if _, isEnum := typeData.(EnumTypeData); isEnum {
+				kind = "enum"
+			}
+		}
+		arguments = append(arguments, "cls="+typeInfo.DataType)
+
+	default:
+		kind = pythonTypes[builtinGoTypes[typeInfo.DataType]]
+		if kind == "str" {
+			kind = "string"
+		}
+
+		if _, ok := formatDescriptions[typeInfo.DataType]; ok {
+			arguments = append(arguments, fmt.Sprintf("format=%q", typeInfo.DataType))
+		}
+
+		if kind == "string" {
+			if typeInfo.Min > 0 {
+				arguments = append(arguments, fmt.Sprintf("min=%v", typeInfo.Min))
+			}
+			if typeInfo.Max > 0 {
+				arguments = append(arguments, fmt.Sprintf("max=%v", typeInfo.Max))
+			}
+			if typeInfo.Pattern != "" {
+				arguments = append(arguments, fmt.Sprintf("pattern=%v", getPythonString(typeInfo.Pattern)))
+			}
+		}
+
+		if minimum := typeInfo.Minimum; minimum != nil {
+			arguments = append(arguments, fmt.Sprintf("minimum=%v", minimum.Value))
+			if minimum.Exclusive {
+				arguments = append(arguments, "exclusive_minimum=True")
+			}
+		}
+		if maximum := typeInfo.Maximum; maximum != nil {
+			arguments = append(arguments, fmt.Sprintf("maximum=%v", maximum.Value))
+			if maximum.Exclusive {
+				arguments = append(arguments, "exclusive_maximum=True")
+			}
+		}
+		if typeInfo.MultipleOf > 0 {
+			arguments = append(arguments, fmt.Sprintf("multiple_of=%v", typeInfo.MultipleOf))
+		}
+	}
+
+	return fmt.Sprintf("_Rules(%v)", strings.Join(append([]string{strconv.Quote(kind)}, arguments...), ", "))
+}
+
+func getPythonValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return getPythonString(text)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// getPythonString returns a Python string literal of value.
+func getPythonString(value string) string {
+	quoted := strconv.Quote(value)
+	// Go escapes of non-printable characters that Python spells differently
+	return strings.NewReplacer(`\a`, `\x07`, `\v`, `\x0b`, `\f`, `\x0c`).Replace(quoted)
+}
+
+// getPythonDecoder returns the expression of a function converting a JSON
+// value of typeInfo into its Python type. Variable fields are decoded by the
+// value of their mapField, one of fields.
+func getPythonDecoder(service *Service, typeInfo TypeInfo, fields StructTypeData) string {
+	decoder := ""
+	switch {
+	case typeInfo.IsVariable:
+		cases := []string{}
+		for _, mappingCase := range typeInfo.Mapping {
+			mapField, _ := findField(fields, typeInfo.MapField)
+			cases = append(cases, fmt.Sprintf("%v: %v", getPythonValue(discriminatorValue(service, mapField.TypeInfo, mappingCase)), getPythonDecoder(service, mappingCase.TypeInfo, nil)))
+		}
+		decoder = fmt.Sprintf("_one_of(data.get(%q), {%v})", typeInfo.MapField, strings.Join(cases, ", "))
+	case typeInfo.IsCustomType:
+		decoder = typeInfo.DataType
+		if typeData, ok := service.lookupType(typeInfo); ok {
+			if _, isStruct := typeData.(StructTypeData); isStruct {
+				decoder += ".from_json"
+			}
+		}
+	default:
+		decoder = "_same"
+	}
+
+	if typeInfo.IsArray {
+		decoder = fmt.Sprintf("_list_of(%v)", decoder)
+	}
+
+	if typeInfo.IsMap {
+		decoder = fmt.Sprintf("_dict_of(%v, %v)", pythonTypes[mapKeyGoTypes[typeInfo.KeyType]], decoder)
+	}
+
+	return decoder
+}
+
+func buildPythonDataclass(service *Service, name string, fields []Parameter) string {
+	// fields with defaults have to follow the ones without them
+	ordered := []Parameter{}
+	for _, field := range fields {
+		if !field.TypeInfo.IsOptional {
+			ordered = append(ordered, field)
+		}
+	}
+	for _, field := range fields {
+		if field.TypeInfo.IsOptional {
+			ordered = append(ordered, field)
+		}
+	}
+
+	text := fmt.Sprintf("\n\n@dataclasses.dataclass\nclass %v:\n", name)
+	for _, field := range ordered {
+		fieldType := getPythonType(field.TypeInfo)
+		if field.TypeInfo.IsOptional {
+			text += fmt.Sprintf("    %v: %v = None\n", getPythonName(string(field.Name)), fieldType)
+		} else {
+			text += fmt.Sprintf("    %v: %v\n", getPythonName(string(field.Name)), fieldType)
+		}
+	}
+	if len(fields) > 0 {
+		text += "\n"
+	}
+
+	validateStatements := ""
+	encodedFields := []string{}
+	decodedFields := ""
+	structData := StructTypeData{}
+	for _, field := range fields {
+		structData = append(structData, Field{Name: FieldName(field.Name), TypeInfo: field.TypeInfo})
+	}
+
+	for _, field := range fields {
+		attribute := getPythonName(string(field.Name))
+
+		validateStatements += fmt.Sprintf("        _validate(errors, join_path(path, %q), self.%v, %v)\n", field.Name, attribute, getPythonRules(service, field.TypeInfo))
+		encodedFields = append(encodedFields, fmt.Sprintf("%q: _encode(self.%v)", field.Name, attribute))
+		decodedFields += fmt.Sprintf("            %v=_decode(data.get(%q), %v),\n", attribute, field.Name, getPythonDecoder(service, field.TypeInfo, structData))
+	}
+
+	if validateStatements == "" {
+		validateStatements = "        pass\n"
+	}
+
+	return text + fmt.Sprintf(`    def validate(self):
+        errors = ValidationErrors()
+        self.validate_at("", errors)
+
+        if errors.errors:
+            raise errors
+
+    def validate_at(self, path: str, errors: ValidationErrors):
+%v
+    def to_json(self) -> typing.Dict[str, typing.Any]:
+        return {%v}
+
+    @classmethod
+    def from_json(cls, data: typing.Dict[str, typing.Any]) -> %v:
+        return cls(
+%v        )
+`, validateStatements, strings.Join(encodedFields, ", "), name, decodedFields)
+}
+
+func buildPythonEnum(name TypeName, data EnumTypeData) string {
+	base := "str"
+	if data.Type == "int" {
+		base = "int"
+	}
+
+	members := ""
+	for _, value := range data.Values {
+		literal := getPythonString(value.StringValue)
+		if data.Type == "int" {
+			literal = strconv.Itoa(value.IntegerValue)
+		}
+
+		members += fmt.Sprintf("    %v = %v\n", getPythonName(strings.Title(value.Name)), literal)
+	}
+
+	return fmt.Sprintf("\n\nclass %v(%v, enum.Enum):\n%v", name, base, members)
+}
+
+func buildPythonErrors(service *Service) string {
+	text := ""
+	declared := []string{}
+
+	for _, definition := range service.Errors {
+		typeName := getErrorTypeName(definition.Name)
+		declared = append(declared, fmt.Sprintf("    %q: %v,\n", definition.Name, typeName))
+
+		parameters := ""
+		optionalParameters := ""
+		assignments := ""
+		decodedFields := ""
+		for _, field := range definition.Data {
+			attribute := getPythonName(string(field.Name))
+
+			if field.TypeInfo.IsOptional {
+				optionalParameters += fmt.Sprintf(", %v: %v = None", attribute, getPythonType(field.TypeInfo))
+			} else {
+				parameters += fmt.Sprintf(", %v: %v", attribute, getPythonType(field.TypeInfo))
+			}
+			assignments += fmt.Sprintf("        self.%v = %v\n", attribute, attribute)
+			decodedFields += fmt.Sprintf("            %v=_decode(data.get(%q), %v),\n", attribute, field.Name, getPythonDecoder(service, field.TypeInfo, definition.Data))
+		}
+
+		text += fmt.Sprintf(`
+
+class %[1]v(RpcError):
+    def __init__(self, message: str%[2]v, data: typing.Any = None%[6]v):
+        super().__init__(%[3]q, message, data)
+%[4]v
+    @classmethod
+    def from_json(cls, message: str, data: typing.Any) -> %[1]v:
+        data = data if isinstance(data, dict) else {}
+        return cls(
+            message,
+            data=data,
+%[5]v        )
+`, typeName, parameters, string(definition.Name), assignments, decodedFields, optionalParameters)
+	}
+
+	return text + fmt.Sprintf("\n\n_DECLARED_ERRORS: typing.Dict[str, typing.Type[RpcError]] = {\n%v}\n", strings.Join(declared, ""))
+}
+
+func buildPythonMethod(service *Service, method Method) string {
+	parameters := ""
+	for _, param := range method.Params {
+		if !param.TypeInfo.IsOptional {
+			parameters += fmt.Sprintf(", %v: %v", getPythonName(string(param.Name)), getPythonType(param.TypeInfo))
+		}
+	}
+	for _, param := range method.Params {
+		if param.TypeInfo.IsOptional {
+			parameters += fmt.Sprintf(", %v: %v = None", getPythonName(string(param.Name)), getPythonType(param.TypeInfo))
+		}
+	}
+
+	arguments := []string{}
+	for _, param := range method.Params {
+		name := getPythonName(string(param.Name))
+		arguments = append(arguments, name+"="+name)
+	}
+
+	return fmt.Sprintf(`
+    def %v(self%v) -> %v:
+        params = %vParams(%v)
+        return self._call(%q, params, %v)
+`, getPythonMethodName(method.Name), parameters, getPythonType(method.Result), strings.Title(string(method.Name)), strings.Join(arguments, ", "), string(method.Name), getPythonDecoder(service, method.Result, nil))
+}
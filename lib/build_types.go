@@ -4,36 +4,31 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"go/format"
+	"hash/fnv"
+	"strconv"
 	"strings"
 )
 
 func buildTypesFile(service *Service) (string, error) {
-	typesFileText := fmt.Sprintf(`
-		package %v
-		import (
-			"fmt"
-			"encoding/json"
-			"github.com/pkg/errors"
-			validator "github.com/asaskevich/govalidator"
-		)
-
+	typesFileText := `
 		type Validatable interface {
 			Validate() error
 		}
 
-	`, service.Package)
+	` + buildPatternVariables(service)
 
-	for name, typeData := range service.Types {
+	for _, definition := range generatedTypes(service) {
 
 		var err error
 		var typeText string
 
-		switch typeData.(type) {
+		name := definition.Name
+		switch typeData := definition.Data.(type) {
 		case StructTypeData:
-			typeText, err = buildStructType(name, typeData.(StructTypeData))
+			typeText, err = buildStructType(name, definition.Documentation, typeData)
 
 		case EnumTypeData:
-			typeText, err = buildEnumType(name, typeData.(EnumTypeData))
+			typeText, err = buildEnumType(name, definition.Documentation, typeData)
 		}
 
 		if err != nil {
@@ -52,8 +47,8 @@ func buildTypesFile(service *Service) (string, error) {
 		//PARAMETERS
 	`
 
-	for methodName, methodData := range service.Methods {
-		paramsText, err := buildParamsForMethod(methodName, methodData)
+	for _, method := range service.Methods {
+		paramsText, err := buildParamsForMethod(method.Name, method.MethodData)
 		if err != nil {
 			return "", err
 		}
@@ -62,9 +57,27 @@ func buildTypesFile(service *Service) (string, error) {
 			/////////////////////////////////////////////////////////////////////
 			//%v
 			%v
-		`, methodName, paramsText)
+		`, method.Name, paramsText)
 	}
 
+	typesFileText += buildErrorsSection(service)
+
+	typesFileText = fmt.Sprintf(`
+		%vpackage %v
+		%v
+		%v
+	`, getDocComment(Documentation{Description: service.Description}), service.Package, buildImports(
+		typesFileText,
+		append([]goImport{
+			{"fmt", "fmt"},
+			{"json", "encoding/json"},
+			{"errors", "github.com/pkg/errors"},
+			{"validator", "github.com/asaskevich/govalidator"},
+			{"regexp", "regexp"},
+			{"exchange", "github.com/akaumov/go-service/exchange"},
+		}, packageGoImports(service)...)...,
+	), typesFileText)
+
 	formattedText, err := format.Source([]byte(typesFileText))
 	if err != nil {
 		return "", fmt.Errorf("can't format code: %v \n\n %v", err, typesFileText)
@@ -73,33 +86,113 @@ func buildTypesFile(service *Service) (string, error) {
 	return string(formattedText), nil
 }
 
-func getGoType(typeInfo TypeInfo) string {
-	var resultType string
+var builtinGoTypes = map[string]string{
+	"uuid":    "string",
+	"int":     "int",
+	"int64":   "int64",
+	"boolean": "bool",
+	"time":    "int64",
+	"string":  "string",
+	"email":   "string",
+
+	"url":      "string",
+	"ipv4":     "string",
+	"ipv6":     "string",
+	"hostname": "string",
+	"date":     "string",
+	"phone":    "string",
+	"hex":      "string",
+	"base64":   "string",
+}
+
+// phones are validated as E.164 numbers with an optional leading "+"
+const phonePattern = `^\+?[1-9][0-9]{6,14}$`
+
+// patternVariable names the package level variable holding the compiled
+// pattern, so every regexp is compiled once at package init.
+func patternVariable(pattern string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(pattern))
+
+	return fmt.Sprintf("pattern%08x", hash.Sum32())
+}
+
+func buildPatternVariables(service *Service) string {
+	patterns := []string{}
+	declared := map[string]bool{}
+
+	addPattern := func(pattern string) {
+		if pattern == "" || declared[pattern] {
+			return
+		}
+		declared[pattern] = true
+		patterns = append(patterns, pattern)
+	}
+
+	for _, generatedService := range generatedServices(service) {
+		generatedService.forEachTypeInfo(func(typeInfo *TypeInfo, what string) {
+			addPattern(typeInfo.Pattern)
+
+			if typeInfo.DataType == "phone" && typeInfo.Namespace == "" {
+				addPattern(phonePattern)
+			}
+		})
+	}
+
+	if len(patterns) == 0 {
+		return ""
+	}
+
+	variables := ""
+	for _, pattern := range patterns {
+		quoted := "`" + pattern + "`"
+		if strings.Contains(pattern, "`") {
+			quoted = strconv.Quote(pattern)
+		}
+
+		variables += fmt.Sprintf("%v = regexp.MustCompile(%v)\n", patternVariable(pattern), quoted)
+	}
 
+	return fmt.Sprintf(`
+		var (
+			%v
+		)
+	`, variables)
+}
+
+var mapKeyGoTypes = map[string]string{
+	"string": "string",
+	"uuid":   "string",
+	"email":  "string",
+	"int":    "int",
+	"int64":  "int64",
+}
+
+// isPassedByPointer reports whether handler params and results of this type
+// are passed as pointers.
+func isPassedByPointer(typeInfo TypeInfo) bool {
+	return (typeInfo.IsCustomType && !typeInfo.IsMap) || typeInfo.IsArray
+}
+
+func getGoType(typeInfo TypeInfo) string {
 	if typeInfo.IsVariable {
 		return "interface{}"
 	}
 
-	switch typeInfo.DataType {
-	case "uuid":
-		resultType = "string"
-	case "int":
-		resultType = "int"
-	case "int64":
-		resultType = "int64"
-	case "boolean":
-		resultType = "bool"
-	case "time":
-		resultType = "int64"
-	case "string":
-		resultType = "string"
-	case "email":
-		resultType = "string"
-
-	default:
+	resultType, isBuiltin := builtinGoTypes[typeInfo.DataType]
+	if !isBuiltin || typeInfo.Namespace != "" {
 		resultType = strings.Title(typeInfo.DataType)
 	}
 
+	if typeInfo.Package != "" {
+		resultType = typeInfo.Namespace + "." + resultType
+	}
+
+	if typeInfo.IsMap {
+		// a nil map already means "absent", so optional maps aren't pointers
+		return fmt.Sprintf("map[%v]%v", mapKeyGoTypes[typeInfo.KeyType], resultType)
+	}
+
 	if typeInfo.IsArray {
 		resultType = "[]" + resultType
 	}
@@ -111,13 +204,41 @@ func getGoType(typeInfo TypeInfo) string {
 	return resultType
 }
 
-func buildStructType(name TypeName, data StructTypeData) (string, error) {
+// getDocComment returns the Go doc comment of documentation. Deprecated items
+// get a "Deprecated:" paragraph, gopls and staticcheck report their uses.
+func getDocComment(documentation Documentation) string {
+	lines := []string{}
+	if description := strings.TrimSpace(documentation.Description); description != "" {
+		lines = strings.Split(description, "\n")
+	}
+
+	if documentation.IsDeprecated {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+
+		deprecation := documentation.Deprecation
+		if deprecation == "" {
+			deprecation = "it will be removed in a future version."
+		}
+		lines = append(lines, "Deprecated: "+deprecation)
+	}
+
+	comment := ""
+	for _, line := range lines {
+		comment += strings.TrimRight("// "+line, " ") + "\n"
+	}
+
+	return comment
+}
+
+func buildStructType(name TypeName, documentation Documentation, data StructTypeData) (string, error) {
 	fieldsText := ""
 
-	for fieldName, fieldTypeInfo := range data {
-		fieldName := string(fieldName)
-		goType := getGoType(fieldTypeInfo)
-		fieldsText = fieldsText + fmt.Sprintf("%v %v `json:\"%v\"`\n", strings.Title(fieldName), goType, fieldName)
+	for _, field := range data {
+		fieldName := string(field.Name)
+		goType := getGoType(field.TypeInfo)
+		fieldsText = fieldsText + getDocComment(field.TypeInfo.Documentation) + fmt.Sprintf("%v %v `json:\"%v\"`\n", strings.Title(fieldName), goType, fieldName)
 	}
 
 	typeValidator, err := getStructTypeValidator(name, data)
@@ -126,7 +247,7 @@ func buildStructType(name TypeName, data StructTypeData) (string, error) {
 	}
 
 	return fmt.Sprintf(`
-		type %v struct {
+		%vtype %v struct {
 			%v 
 		}
 		%v
@@ -141,21 +262,21 @@ func buildStructType(name TypeName, data StructTypeData) (string, error) {
 
 			return string(jsonRepresentation)
 		}
-	`, name, fieldsText, typeValidator, getUnmarshaller(name, data), name), nil
+	`, getDocComment(documentation), name, fieldsText, typeValidator, getUnmarshaller(name, data), name), nil
 }
 
-func buildEnumType(name TypeName, data EnumTypeData) (string, error) {
+func buildEnumType(name TypeName, documentation Documentation, data EnumTypeData) (string, error) {
 
 	typeName := strings.Title(string(name))
 	valuesText := ""
 
 	if data.Type == "int" {
-		for valueName, value := range data.ValuesInteger {
-			valuesText = valuesText + fmt.Sprintf("%v %v = %v\n", strings.Title(valueName), typeName, value)
+		for _, value := range data.Values {
+			valuesText = valuesText + getDocComment(value.Documentation) + fmt.Sprintf("%v %v = %v\n", strings.Title(value.Name), typeName, value.IntegerValue)
 		}
 	} else if data.Type == "string" {
-		for valueName, value := range data.ValuesString {
-			valuesText = valuesText + fmt.Sprintf("%v %v = \"%v\"\n", strings.Title(strings.Replace(valueName, "\"", "\"", -1)), typeName, value)
+		for _, value := range data.Values {
+			valuesText = valuesText + getDocComment(value.Documentation) + fmt.Sprintf("%v %v = \"%v\"\n", strings.Title(value.Name), typeName, value.StringValue)
 		}
 	} else {
 		return "", errors.New("wrong enum type")
@@ -167,28 +288,26 @@ func buildEnumType(name TypeName, data EnumTypeData) (string, error) {
 	}
 
 	return fmt.Sprintf(`
-		type %v %v 
+		%vtype %v %v 
 
 		const (
 			%v 
 		)
 
 		%v
-	`, typeName, data.Type, valuesText, validator), nil
+	`, getDocComment(documentation), typeName, data.Type, valuesText, validator), nil
 }
 
 func getUnmarshaller(typeName TypeName, fields StructTypeData) string {
 
-	variableFields := map[string]TypeInfo{}
-	plainFields := map[string]TypeInfo{}
+	variableFields := StructTypeData{}
+	plainFields := StructTypeData{}
 
-	for fieldName, fieldTypeInfo := range fields {
-		fieldName := strings.Title(string(fieldName))
-
-		if fieldTypeInfo.IsVariable {
-			variableFields[fieldName] = fieldTypeInfo
+	for _, field := range fields {
+		if field.TypeInfo.IsVariable {
+			variableFields = append(variableFields, field)
 		} else {
-			plainFields[fieldName] = fieldTypeInfo
+			plainFields = append(plainFields, field)
 		}
 	}
 
@@ -198,20 +317,22 @@ func getUnmarshaller(typeName TypeName, fields StructTypeData) string {
 
 	commonFields := ""
 	commonFieldsAssignment := ""
-	for fieldName, fieldTypeInfo := range plainFields {
-		commonFields += fmt.Sprintf("%v %v \n", fieldName, getGoType(fieldTypeInfo))
+	for _, field := range plainFields {
+		fieldName := strings.Title(string(field.Name))
+		commonFields += fmt.Sprintf("%v %v \n", fieldName, getGoType(field.TypeInfo))
 		commonFieldsAssignment += fmt.Sprintf("v.%v = commonFields.%v \n", fieldName, fieldName)
 	}
 
 	rawVariableFields := ""
 	variableFieldsUnmarshal := ""
 
-	for fieldName, fieldTypeInfo := range variableFields {
+	for _, field := range variableFields {
+		fieldName := strings.Title(string(field.Name))
 		rawVariableFields += fmt.Sprintf("%v json.RawMessage \n", fieldName)
-		mapField := fieldTypeInfo.MapField
+		mapField := field.TypeInfo.MapField
 
 		cases := ""
-		for value, mappingTypeInfo := range fieldTypeInfo.Mapping {
+		for _, mappingCase := range field.TypeInfo.Mapping {
 			cases += fmt.Sprintf(`
 				case "%v":
 					var parsedData %v
@@ -222,7 +343,7 @@ func getUnmarshaller(typeName TypeName, fields StructTypeData) string {
 
 					v.%v = parsedData
 					break
-			`, value, getGoType(mappingTypeInfo), fieldName, fieldName)
+			`, mappingCase.Value, getGoType(mappingCase.TypeInfo), fieldName, fieldName)
 		}
 
 		variableFieldsUnmarshal += fmt.Sprintf(`
@@ -255,213 +376,6 @@ func getUnmarshaller(typeName TypeName, fields StructTypeData) string {
 	`, typeName, commonFields, rawVariableFields, commonFieldsAssignment, variableFieldsUnmarshal)
 }
 
-func getEnumTypeValidator(name TypeName, data EnumTypeData) (string, error) {
-	cases := ""
-
-	if data.Type == "int" {
-		for valueName, _ := range data.ValuesInteger {
-			cases += fmt.Sprintf("case %v:\n return nil\n", strings.Title(valueName))
-		}
-	} else if data.Type == "string" {
-		for valueName, _ := range data.ValuesString {
-			cases += fmt.Sprintf("case %v:\n return nil\n", strings.Title(valueName))
-		}
-	} else {
-		return "", errors.New("wrong enum type")
-	}
-
-	return fmt.Sprintf(`
-		func(v %v) Validate() error {
-			switch v {
-			%v
-			}
-
-			return fmt.Errorf("no such value: %%v", v)
-		}
-	`, name, cases), nil
-}
-
-func getStructTypeValidator(name TypeName, fields StructTypeData) (string, error) {
-	conditions := ""
-
-	for fieldName, fieldTypeInfo := range fields {
-		fieldName := strings.Title(string(fieldName))
-
-		condition := getValidateCondition("value", fieldTypeInfo)
-		if condition == "true" {
-			continue
-		}
-
-		conditions += fmt.Sprintf(`
-			 {
-				value := v.%v
-				isValid := %v
-
-				if !isValid {
-					return fmt.Errorf("%v is invalid")
-				}
-			}
-		`, fieldName, condition, fieldName)
-	}
-
-	if conditions == "" {
-		return fmt.Sprintf(`
-			func(v *%v) Validate() error {
-				return nil
-			}
-		`, name), nil
-	}
-
-	return fmt.Sprintf(`
-		func(v *%v) Validate() error {
-			%v
-			return nil
-		}
-	`, name, conditions), nil
-}
-
-func getValidateCondition(valueName string, typeInfo TypeInfo) string {
-	if typeInfo.IsVariable {
-		return getValidateConditionForVariableValue(valueName, typeInfo)
-	}
-
-	if typeInfo.IsArray {
-		return getValidateConditionForArrayValue(valueName, typeInfo)
-	}
-
-	if typeInfo.IsCustomType {
-		return getValidateConditionForCustomType(valueName, typeInfo)
-	}
-
-	return getValidateConditionForSimpleValue(valueName, typeInfo)
-}
-
-func getValidateConditionForCustomType(valueName string, typeInfo TypeInfo) string {
-	if typeInfo.IsOptional {
-		return fmt.Sprintf("%v == nil || (%v.Validate() == nil)", valueName, valueName)
-	}
-
-	return fmt.Sprintf("%v.Validate() == nil", valueName)
-}
-
-func getValidateConditionForVariableValue(valueName string, typeInfo TypeInfo) string {
-	if typeInfo.IsOptional {
-		//TODO
-		return "true"
-	}
-
-	cases := ""
-	for _, mapTypeInfo := range typeInfo.Mapping {
-
-		goType := getGoType(mapTypeInfo)
-
-		cases += fmt.Sprintf(`
-				case %v:
-					x := value.(%v)
-					return (&x).Validate() == nil
-			`, goType, goType)
-	}
-
-	return fmt.Sprintf(
-		`func (value interface{}) bool {
-				switch(value.(type)) {
-					%v
-					default:
-						panic("not implemented")
-				}
-			}(%v)
-		`, cases, valueName)
-}
-
-func getValidateConditionForSimpleValue(valueName string, typeInfo TypeInfo) string {
-
-	nilCheck := fmt.Sprintf("%v == nil || ", valueName)
-
-	switch typeInfo.DataType {
-	case "uuid":
-		if typeInfo.IsOptional {
-			return fmt.Sprintf("%v validator.IsUUID(*%v)", nilCheck, valueName)
-		}
-		return fmt.Sprintf("validator.IsUUID(%v)", valueName)
-
-	case "email":
-		if typeInfo.IsOptional {
-			return fmt.Sprintf("%v validator.IsEmail(*%v)", nilCheck, valueName)
-		}
-		return fmt.Sprintf("validator.IsEmail(%v)", valueName)
-
-	case "string":
-		if typeInfo.IsOptional {
-			return fmt.Sprintf(
-				"%v (%v)",
-				nilCheck, getLengthCondition("*"+valueName, typeInfo.Min, typeInfo.Max))
-		}
-
-		return getLengthCondition(valueName, typeInfo.Min, typeInfo.Max)
-	}
-
-	return "true"
-}
-
-func getValidateConditionForArrayValue(valueName string, typeInfo TypeInfo) string {
-
-	goType := getGoType(typeInfo)
-
-	lengthCondition := getLengthCondition(valueName, typeInfo.Min, typeInfo.Max)
-	if lengthCondition == "true" {
-		lengthCondition = ""
-	} else {
-		lengthCondition += " &&\n"
-	}
-
-	itemCondition := "item.Validate() == nil"
-	if !typeInfo.IsCustomType {
-		itemCondition = getValidateConditionForSimpleValue("item", typeInfo)
-	}
-
-	value := "&" + valueName
-	if typeInfo.IsOptional {
-		value = valueName
-	}
-
-	itemsCondition := fmt.Sprintf(
-		`func (value *%v) bool {
-				for _, item := range *value {
-					isValid := %v
-
-					if !isValid {
-						return false
-					}
-				}
-				return true
-			} (%v)
-	`, goType, itemCondition, value)
-
-	if typeInfo.IsOptional {
-		return fmt.Sprintf("%v == nil || (%v %v)", valueName, lengthCondition, itemsCondition)
-	}
-
-	return lengthCondition + itemsCondition
-}
-
-func getLengthCondition(valueName string, min int, max int) string {
-	if max > 0 {
-		return fmt.Sprintf(
-			"(len(%v) >= %v && len(%v) <= %v)",
-			valueName, min, valueName, max,
-		)
-
-	} else if min > 0 {
-
-		return fmt.Sprintf(
-			"(len(%v) >= %v)",
-			valueName, min,
-		)
-	}
-
-	return "true"
-}
-
 func buildParamsForMethod(methodName MethodName, methodData MethodData) (string, error) {
 	name := strings.Title(string(methodName)) + "Params"
 	fieldsText := ""
@@ -472,8 +386,8 @@ func buildParamsForMethod(methodName MethodName, methodData MethodData) (string,
 		paramType := paramData.TypeInfo
 
 		goType := getGoType(paramType)
-		fieldsText = fieldsText + fmt.Sprintf("%v %v `json:\"%v\"`\n", strings.Title(paramName), goType, paramName)
-		fields[FieldName(paramName)] = paramType
+		fieldsText = fieldsText + getDocComment(paramType.Documentation) + fmt.Sprintf("%v %v `json:\"%v\"`\n", strings.Title(paramName), goType, paramName)
+		fields = append(fields, Field{Name: FieldName(paramName), TypeInfo: paramType})
 	}
 
 	paramsValidator, err := getStructTypeValidator(TypeName(name), fields)
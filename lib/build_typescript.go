@@ -0,0 +1,327 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var typeScriptTypes = map[string]string{
+	"int":    "number",
+	"int64":  "number",
+	"bool":   "boolean",
+	"string": "string",
+}
+
+func buildTypeScriptFile(service *Service) (string, error) {
+	text := `//!!!GENERATED BY "GO-SERVICE" DON'T CHANGE THIS FILE!!!
+`
+
+	types, err := allTypes(service)
+	if err != nil {
+		return "", err
+	}
+
+	for _, serviceType := range types {
+		switch typeData := serviceType.Data.(type) {
+		case StructTypeData:
+			text += buildTypeScriptStruct(serviceType.Service, serviceType.Name, typeData)
+		case EnumTypeData:
+			text += buildTypeScriptEnum(serviceType.Name, typeData)
+		}
+	}
+
+	for _, method := range service.Methods {
+		text += buildTypeScriptInterface(strings.Title(string(method.Name))+"Params", method.Params)
+		text += fmt.Sprintf("\nexport type %vResult = %v;\n", strings.Title(string(method.Name)), getTypeScriptType(method.Result))
+	}
+
+	text += buildTypeScriptErrors(service)
+	text += buildTypeScriptClient(service)
+
+	return text, nil
+}
+
+type serviceType struct {
+	TypeDefinition
+	Service *Service
+}
+
+// discriminatorValue returns the JSON value of the mapField of a variable
+// field selecting mappingCase: integers for int enums, strings otherwise.
+func discriminatorValue(service *Service, mapFieldTypeInfo TypeInfo, mappingCase MappingCase) interface{} {
+	typeData, _ := service.lookupType(mapFieldTypeInfo)
+	if enumData, ok := typeData.(EnumTypeData); ok && enumData.Type == "int" {
+		for _, value := range enumData.Values {
+			if value.StringValue == mappingCase.Value {
+				return value.IntegerValue
+			}
+		}
+	}
+
+	return mappingCase.Value
+}
+
+// allTypes returns the types of service and of everything it imports, for
+// targets without namespaces: names defined by more than one schema are an error.
+func allTypes(service *Service) ([]serviceType, error) {
+	types := []serviceType{}
+	definedIn := map[TypeName]*Service{}
+
+	for _, typeService := range allServices(service) {
+		for _, definition := range typeService.Types {
+			if previous, ok := definedIn[definition.Name]; ok && previous != typeService {
+				return nil, fmt.Errorf("type %v is defined by more than one schema, generated types share a single namespace", definition.Name)
+			}
+			definedIn[definition.Name] = typeService
+
+			types = append(types, serviceType{TypeDefinition: definition, Service: typeService})
+		}
+	}
+
+	return types, nil
+}
+
+// allServices returns service and everything it imports, with or without GoPackage.
+func allServices(service *Service) []*Service {
+	result := []*Service{}
+	visited := map[*Service]bool{}
+
+	var visit func(service *Service)
+	visit = func(service *Service) {
+		if service == nil || visited[service] {
+			return
+		}
+		visited[service] = true
+		result = append(result, service)
+
+		for _, schemaImport := range service.Imports {
+			visit(schemaImport.Service)
+		}
+	}
+	visit(service)
+
+	return result
+}
+
+func getTypeScriptType(typeInfo TypeInfo) string {
+	resultType := typeInfo.DataType
+	if !typeInfo.IsCustomType {
+		resultType = typeScriptTypes[builtinGoTypes[typeInfo.DataType]]
+	}
+
+	if typeInfo.IsArray {
+		resultType += "[]"
+	}
+
+	if typeInfo.IsMap {
+		resultType = fmt.Sprintf("{ [key: string]: %v }", resultType)
+	}
+
+	return resultType
+}
+
+func buildTypeScriptField(name string, typeInfo TypeInfo) string {
+	if typeInfo.IsOptional {
+		return fmt.Sprintf("  %v?: %v | null;\n", name, getTypeScriptType(typeInfo))
+	}
+
+	return fmt.Sprintf("  %v: %v;\n", name, getTypeScriptType(typeInfo))
+}
+
+func buildTypeScriptInterface(name string, params []Parameter) string {
+	fields := ""
+	for _, param := range params {
+		fields += buildTypeScriptField(string(param.Name), param.TypeInfo)
+	}
+
+	if fields == "" {
+		return fmt.Sprintf("\nexport interface %v {}\n", name)
+	}
+
+	return fmt.Sprintf("\nexport interface %v {\n%v}\n", name, fields)
+}
+
+func buildTypeScriptEnum(name TypeName, data EnumTypeData) string {
+	values := []string{}
+	constants := ""
+	for _, value := range data.Values {
+		literal := strconv.Quote(value.StringValue)
+		if data.Type == "int" {
+			literal = strconv.FormatInt(int64(value.IntegerValue), 10)
+		}
+
+		values = append(values, literal)
+		constants += fmt.Sprintf("  %v: %v,\n", strings.Title(value.Name), literal)
+	}
+
+	return fmt.Sprintf(`
+export type %[1]v = %[2]v;
+
+export const %[1]v = {
+%[3]v} as const;
+`, name, strings.Join(values, " | "), constants)
+}
+
+// buildTypeScriptStruct emits structs with variable fields as discriminated
+// unions on their mapField.
+func buildTypeScriptStruct(service *Service, name TypeName, data StructTypeData) string {
+	mapFields := map[FieldName]bool{}
+	for _, field := range data {
+		if field.TypeInfo.IsVariable {
+			mapFields[field.TypeInfo.MapField] = true
+		}
+	}
+
+	if len(mapFields) == 0 {
+		fields := ""
+		for _, field := range data {
+			fields += buildTypeScriptField(string(field.Name), field.TypeInfo)
+		}
+
+		return fmt.Sprintf("\nexport interface %v {\n%v}\n", name, fields)
+	}
+
+	fields := ""
+	unions := []string{}
+	for _, field := range data {
+		if mapFields[field.Name] {
+			continue
+		}
+
+		if !field.TypeInfo.IsVariable {
+			fields += buildTypeScriptField(string(field.Name), field.TypeInfo)
+			continue
+		}
+
+		unions = append(unions, buildTypeScriptUnion(service, field, data))
+	}
+
+	return fmt.Sprintf(`
+interface %[1]vBase {
+%[2]v}
+
+export type %[1]v = %[1]vBase & %[3]v;
+`, name, fields, strings.Join(unions, " & "))
+}
+
+func buildTypeScriptUnion(service *Service, field Field, data StructTypeData) string {
+	mapField, _ := findField(data, field.TypeInfo.MapField)
+
+	cases := ""
+	for _, mappingCase := range field.TypeInfo.Mapping {
+		value := discriminatorValue(service, mapField.TypeInfo, mappingCase)
+
+		literal := fmt.Sprint(value)
+		if _, isString := value.(string); isString {
+			literal = strconv.Quote(literal)
+		}
+
+		cases += fmt.Sprintf("\n  | { %v: %v; %v: %v }", mapField.Name, literal, field.Name, getTypeScriptType(mappingCase.TypeInfo))
+	}
+
+	return fmt.Sprintf("(%v\n)", cases)
+}
+
+func buildTypeScriptErrors(service *Service) string {
+	if len(service.Errors) == 0 {
+		return ""
+	}
+
+	codes := ""
+	text := ""
+	for _, definition := range service.Errors {
+		codes += fmt.Sprintf("  %v: %v,\n", definition.Name, definition.Code)
+
+		if len(definition.Data) > 0 {
+			fields := ""
+			for _, field := range definition.Data {
+				fields += buildTypeScriptField(string(field.Name), field.TypeInfo)
+			}
+
+			text += fmt.Sprintf("\nexport interface %vData {\n%v}\n", getErrorTypeName(definition.Name), fields)
+		}
+	}
+
+	return fmt.Sprintf(`
+export const ErrorCodes = {
+%v} as const;
+%v`, codes, text)
+}
+
+func buildTypeScriptClient(service *Service) string {
+	methods := ""
+	for _, method := range service.Methods {
+		name := strings.Title(string(method.Name))
+
+		if len(method.Params) == 0 {
+			methods += fmt.Sprintf(`
+  %v(): Promise<%vResult> {
+    return this.call(%q, {});
+  }
+`, method.Name, name, method.Name)
+			continue
+		}
+
+		methods += fmt.Sprintf(`
+  %v(params: %vParams): Promise<%vResult> {
+    return this.call(%q, params);
+  }
+`, method.Name, name, name, method.Name)
+	}
+
+	return fmt.Sprintf(`
+// Transport sends a JSON-RPC 2.0 request and resolves to the response, or to
+// null if there is nothing to respond.
+export interface Transport {
+  roundTrip(request: string): Promise<string | null>;
+}
+
+export function httpTransport(url: string, init: RequestInit = {}): Transport {
+  return {
+    async roundTrip(request: string): Promise<string | null> {
+      const response = await fetch(url, {
+        ...init,
+        method: "POST",
+        headers: { ...(init.headers as Record<string, string>), "Content-Type": "application/json" },
+        body: request,
+      });
+
+      if (response.status === 204) {
+        return null;
+      }
+
+      return response.text();
+    },
+  };
+}
+
+export class RpcError extends Error {
+  constructor(readonly code: number, message: string, readonly data?: unknown) {
+    super(message);
+    this.name = "RpcError";
+  }
+}
+
+export class Client {
+  private lastId = 0;
+
+  constructor(private readonly transport: Transport) {}
+
+  private async call<T>(method: string, params: object): Promise<T> {
+    const id = ++this.lastId;
+    const response = await this.transport.roundTrip(JSON.stringify({ jsonrpc: "2.0", id, method, params }));
+    if (response === null) {
+      throw new RpcError(-32603, "no response to " + method);
+    }
+
+    const message = JSON.parse(response);
+    if (message.error) {
+      throw new RpcError(message.error.code, message.error.message, message.error.data);
+    }
+
+    return message.result as T;
+  }
+%v}
+`, methods)
+}
@@ -0,0 +1,450 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Generated types validate themselves into an exchange.ValidationErrors list:
+// Validate() collects every violation of a value, ValidateAt(path, errs) is
+// used by enclosing types to report violations under their JSON path.
+
+var formatValidators = map[string]string{
+	"uuid":     "validator.IsUUID(%v)",
+	"email":    "validator.IsEmail(%v)",
+	"url":      "validator.IsURL(%v)",
+	"ipv4":     "validator.IsIPv4(%v)",
+	"ipv6":     "validator.IsIPv6(%v)",
+	"hostname": "validator.IsDNSName(%v)",
+	"date":     `validator.IsTime(%v, "2006-01-02")`,
+	"hex":      "validator.IsHexadecimal(%v)",
+	"base64":   "validator.IsBase64(%v)",
+}
+
+var formatDescriptions = map[string]string{
+	"uuid":     "a valid uuid",
+	"email":    "a valid email",
+	"url":      "a valid url",
+	"ipv4":     "a valid IPv4 address",
+	"ipv6":     "a valid IPv6 address",
+	"hostname": "a valid hostname",
+	"date":     "a date formatted as YYYY-MM-DD",
+	"phone":    "a valid phone number",
+	"hex":      "a hexadecimal string",
+	"base64":   "a base64 string",
+}
+
+var itemVariableRegexp = regexp.MustCompile(`\bitem\b`)
+
+type valueCheck struct {
+	condition string
+	rule      string
+	message   string
+	params    string
+}
+
+func buildValidateMethods(receiver string, name TypeName, body string) string {
+	return fmt.Sprintf(`
+		func(v %v) Validate() error {
+			errs := exchange.ValidationErrors{}
+			v.ValidateAt("", &errs)
+
+			if len(errs) > 0 {
+				return errs
+			}
+
+			return nil
+		}
+
+		func(v %v) ValidateAt(path string, errs *exchange.ValidationErrors) {
+			%v
+		}
+	`, receiver+strings.Title(string(name)), receiver+strings.Title(string(name)), body)
+}
+
+func getEnumTypeValidator(name TypeName, data EnumTypeData) (string, error) {
+	if data.Type != "int" && data.Type != "string" {
+		return "", fmt.Errorf("wrong enum type of %v", name)
+	}
+
+	constants := []string{}
+	values := []string{}
+	for _, value := range data.Values {
+		constants = append(constants, strings.Title(value.Name))
+		values = append(values, value.StringValue)
+	}
+
+	body := fmt.Sprintf(`
+		switch v {
+		case %v:
+			return
+		}
+
+		errs.Add(path, "enum", %q, map[string]interface{}{"values": []%v{%v}})
+	`,
+		strings.Join(constants, ", "),
+		"must be one of: "+strings.Join(values, ", "),
+		strings.Title(string(name)), strings.Join(constants, ", "),
+	)
+
+	return buildValidateMethods("", name, body), nil
+}
+
+func getStructTypeValidator(name TypeName, fields StructTypeData) (string, error) {
+	body := ""
+
+	for _, field := range fields {
+		statements := getValidateStatements("value", "fieldPath", field.TypeInfo)
+		if statements == "" {
+			continue
+		}
+
+		body += fmt.Sprintf(`
+			{
+				value := v.%v
+				fieldPath := exchange.JoinPath(path, %q)
+				%v
+			}
+		`, strings.Title(string(field.Name)), string(field.Name), statements)
+	}
+
+	return buildValidateMethods("*", name, body), nil
+}
+
+// getValidateStatements returns the statements reporting every violation of
+// typeInfo constraints by valueName into errs, or "" if there is nothing to check.
+func getValidateStatements(valueName string, pathName string, typeInfo TypeInfo) string {
+	if typeInfo.IsVariable {
+		return getVariableValueValidateStatements(valueName, pathName, typeInfo)
+	}
+
+	if typeInfo.IsMap {
+		return getMapValueValidateStatements(valueName, pathName, typeInfo)
+	}
+
+	if typeInfo.IsArray {
+		return getArrayValueValidateStatements(valueName, pathName, typeInfo)
+	}
+
+	if typeInfo.IsCustomType {
+		if typeInfo.IsOptional {
+			return fmt.Sprintf(`
+				if %v != nil {
+					%v.ValidateAt(%v, errs)
+				}
+			`, valueName, valueName, pathName)
+		}
+
+		return fmt.Sprintf("%v.ValidateAt(%v, errs)\n", valueName, pathName)
+	}
+
+	return getSimpleValueValidateStatements(valueName, pathName, typeInfo)
+}
+
+func getRequiredStatement(pathName string) string {
+	return fmt.Sprintf(`errs.Add(%v, "required", "is required", nil)`, pathName)
+}
+
+func getVariableValueValidateStatements(valueName string, pathName string, typeInfo TypeInfo) string {
+	cases := ""
+	for _, mappingCase := range typeInfo.Mapping {
+		cases += fmt.Sprintf(`
+			case %v:
+				%v
+		`, getGoType(mappingCase.TypeInfo), getValidateStatements("x", pathName, mappingCase.TypeInfo))
+	}
+
+	nilCase := ""
+	if !typeInfo.IsOptional {
+		nilCase = fmt.Sprintf(`
+			case nil:
+				%v
+		`, getRequiredStatement(pathName))
+	} else {
+		nilCase = "case nil:\n"
+	}
+
+	return fmt.Sprintf(`
+		switch x := %v.(type) {
+			%v
+			%v
+			default:
+				errs.Add(%v, "type", fmt.Sprintf("has unexpected type %%T", x), nil)
+		}
+	`, valueName, cases, nilCase, pathName)
+}
+
+func getCountChecks(valueName string, typeInfo TypeInfo) []valueCheck {
+	checks := []valueCheck{}
+
+	if typeInfo.Min > 0 {
+		checks = append(checks, valueCheck{
+			condition: fmt.Sprintf("len(%v) >= %v", valueName, typeInfo.Min),
+			rule:      "minItems",
+			message:   fmt.Sprintf("must contain at least %v items", typeInfo.Min),
+			params:    fmt.Sprintf(`map[string]interface{}{"min": %v}`, typeInfo.Min),
+		})
+	}
+
+	if typeInfo.Max > 0 {
+		checks = append(checks, valueCheck{
+			condition: fmt.Sprintf("len(%v) <= %v", valueName, typeInfo.Max),
+			rule:      "maxItems",
+			message:   fmt.Sprintf("must contain at most %v items", typeInfo.Max),
+			params:    fmt.Sprintf(`map[string]interface{}{"max": %v}`, typeInfo.Max),
+		})
+	}
+
+	return checks
+}
+
+func getArrayValueValidateStatements(valueName string, pathName string, typeInfo TypeInfo) string {
+	itemTypeInfo := typeInfo
+	itemTypeInfo.IsArray = false
+	itemTypeInfo.IsOptional = false
+	itemTypeInfo.Min = 0
+	itemTypeInfo.Max = -1
+
+	statements := buildChecks(getCountChecks("items", typeInfo), pathName)
+
+	if itemStatements := getValidateStatements("item", "itemPath", itemTypeInfo); itemStatements != "" {
+		statements += fmt.Sprintf(`
+			for index, item := range items {
+				itemPath := exchange.IndexPath(%v, index)
+				%v
+			}
+		`, pathName, itemStatements)
+	}
+
+	if statements == "" {
+		return ""
+	}
+
+	if typeInfo.IsOptional {
+		return fmt.Sprintf(`
+			if %v != nil {
+				items := *%v
+				%v
+			}
+		`, valueName, valueName, statements)
+	}
+
+	return fmt.Sprintf(`
+		{
+			items := %v
+			%v
+		}
+	`, valueName, statements)
+}
+
+func getMapValueValidateStatements(valueName string, pathName string, typeInfo TypeInfo) string {
+	itemTypeInfo := typeInfo
+	itemTypeInfo.IsMap = false
+	itemTypeInfo.IsOptional = false
+	itemTypeInfo.Min = 0
+	itemTypeInfo.Max = -1
+
+	keyChecks := []valueCheck{}
+	if validatorFormat, ok := formatValidators[typeInfo.KeyType]; ok {
+		keyChecks = append(keyChecks, valueCheck{
+			condition: fmt.Sprintf(validatorFormat, "key"),
+			rule:      typeInfo.KeyType,
+			message:   "key must be " + formatDescriptions[typeInfo.KeyType],
+			params:    "nil",
+		})
+	}
+
+	keyPath := "key"
+	if mapKeyGoTypes[typeInfo.KeyType] != "string" {
+		keyPath = "fmt.Sprint(key)"
+	}
+
+	itemStatements := getValidateStatements("item", "itemPath", itemTypeInfo) + buildChecks(keyChecks, "itemPath")
+
+	entriesStatements := ""
+	if itemStatements != "" {
+		variables := "key"
+		if itemVariableRegexp.MatchString(itemStatements) {
+			variables = "key, item"
+		}
+
+		entriesStatements = fmt.Sprintf(`
+			for %v := range %v {
+				itemPath := exchange.KeyPath(%v, %v)
+				%v
+			}
+		`, variables, valueName, pathName, keyPath, itemStatements)
+	}
+
+	statements := buildChecks(getCountChecks(valueName, typeInfo), pathName) + entriesStatements
+
+	if typeInfo.IsOptional {
+		if statements == "" {
+			return ""
+		}
+
+		return fmt.Sprintf(`
+			if %v != nil {
+				%v
+			}
+		`, valueName, statements)
+	}
+
+	if statements == "" {
+		return fmt.Sprintf(`
+			if %v == nil {
+				%v
+			}
+		`, valueName, getRequiredStatement(pathName))
+	}
+
+	return fmt.Sprintf(`
+		if %v == nil {
+			%v
+		} else {
+			%v
+		}
+	`, valueName, getRequiredStatement(pathName), statements)
+}
+
+func getSimpleValueValidateStatements(valueName string, pathName string, typeInfo TypeInfo) string {
+	value := valueName
+	if typeInfo.IsOptional {
+		value = "*" + valueName
+	}
+
+	checks := []valueCheck{}
+	if validatorFormat, ok := formatValidators[typeInfo.DataType]; ok {
+		checks = append(checks, valueCheck{
+			condition: fmt.Sprintf(validatorFormat, value),
+			rule:      typeInfo.DataType,
+			message:   "must be " + formatDescriptions[typeInfo.DataType],
+			params:    "nil",
+		})
+	}
+
+	switch builtinGoTypes[typeInfo.DataType] {
+	case "string":
+		checks = append(checks, getLengthChecks(value, typeInfo)...)
+
+		if typeInfo.DataType == "phone" {
+			checks = append(checks, valueCheck{
+				condition: fmt.Sprintf("%v.MatchString(%v)", patternVariable(phonePattern), value),
+				rule:      "phone",
+				message:   "must be " + formatDescriptions["phone"],
+				params:    "nil",
+			})
+		}
+
+		if typeInfo.Pattern != "" {
+			checks = append(checks, valueCheck{
+				condition: fmt.Sprintf("%v.MatchString(%v)", patternVariable(typeInfo.Pattern), value),
+				rule:      "pattern",
+				message:   "must match pattern " + typeInfo.Pattern,
+				params:    fmt.Sprintf(`map[string]interface{}{"pattern": %v}`, strconv.Quote(typeInfo.Pattern)),
+			})
+		}
+
+	case "int", "int64":
+		checks = append(checks, getRangeChecks(value, typeInfo)...)
+	}
+
+	statements := buildChecks(checks, pathName)
+	if statements == "" || !typeInfo.IsOptional {
+		return statements
+	}
+
+	return fmt.Sprintf(`
+		if %v != nil {
+			%v
+		}
+	`, valueName, statements)
+}
+
+func getLengthChecks(valueName string, typeInfo TypeInfo) []valueCheck {
+	checks := []valueCheck{}
+
+	if typeInfo.Min > 0 {
+		checks = append(checks, valueCheck{
+			condition: fmt.Sprintf("len(%v) >= %v", valueName, typeInfo.Min),
+			rule:      "minLength",
+			message:   fmt.Sprintf("must be at least %v characters long", typeInfo.Min),
+			params:    fmt.Sprintf(`map[string]interface{}{"min": %v}`, typeInfo.Min),
+		})
+	}
+
+	if typeInfo.Max > 0 {
+		checks = append(checks, valueCheck{
+			condition: fmt.Sprintf("len(%v) <= %v", valueName, typeInfo.Max),
+			rule:      "maxLength",
+			message:   fmt.Sprintf("must be at most %v characters long", typeInfo.Max),
+			params:    fmt.Sprintf(`map[string]interface{}{"max": %v}`, typeInfo.Max),
+		})
+	}
+
+	return checks
+}
+
+func getRangeChecks(valueName string, typeInfo TypeInfo) []valueCheck {
+	checks := []valueCheck{}
+
+	if minimum := typeInfo.Minimum; minimum != nil {
+		check := valueCheck{
+			condition: fmt.Sprintf("%v >= %v", valueName, minimum.Value),
+			rule:      "minimum",
+			message:   fmt.Sprintf("must be greater than or equal to %v", minimum.Value),
+			params:    fmt.Sprintf(`map[string]interface{}{"minimum": %v}`, minimum.Value),
+		}
+
+		if minimum.Exclusive {
+			check.condition = fmt.Sprintf("%v > %v", valueName, minimum.Value)
+			check.rule = "exclusiveMinimum"
+			check.message = fmt.Sprintf("must be greater than %v", minimum.Value)
+		}
+
+		checks = append(checks, check)
+	}
+
+	if maximum := typeInfo.Maximum; maximum != nil {
+		check := valueCheck{
+			condition: fmt.Sprintf("%v <= %v", valueName, maximum.Value),
+			rule:      "maximum",
+			message:   fmt.Sprintf("must be less than or equal to %v", maximum.Value),
+			params:    fmt.Sprintf(`map[string]interface{}{"maximum": %v}`, maximum.Value),
+		}
+
+		if maximum.Exclusive {
+			check.condition = fmt.Sprintf("%v < %v", valueName, maximum.Value)
+			check.rule = "exclusiveMaximum"
+			check.message = fmt.Sprintf("must be less than %v", maximum.Value)
+		}
+
+		checks = append(checks, check)
+	}
+
+	if typeInfo.MultipleOf > 0 {
+		checks = append(checks, valueCheck{
+			condition: fmt.Sprintf("%v%%%v == 0", valueName, typeInfo.MultipleOf),
+			rule:      "multipleOf",
+			message:   fmt.Sprintf("must be a multiple of %v", typeInfo.MultipleOf),
+			params:    fmt.Sprintf(`map[string]interface{}{"multipleOf": %v}`, typeInfo.MultipleOf),
+		})
+	}
+
+	return checks
+}
+
+func buildChecks(checks []valueCheck, pathName string) string {
+	statements := ""
+	for _, check := range checks {
+		statements += fmt.Sprintf(`
+			if !(%v) {
+				errs.Add(%v, %q, %q, %v)
+			}
+		`, check.condition, pathName, check.rule, check.message, check.params)
+	}
+
+	return statements
+}
@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Diagnostic struct {
+	File string `json:"file"`
+	Position
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location = fmt.Sprintf("%v:%v:%v", d.File, d.Line, d.Column)
+	}
+
+	if location == "" {
+		return d.Message
+	}
+
+	return fmt.Sprintf("%v: %v", location, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, 0, len(d))
+	for _, diagnostic := range d {
+		lines = append(lines, diagnostic.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (d *Diagnostics) add(position Position, format string, args ...interface{}) {
+	*d = append(*d, Diagnostic{
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d Diagnostics) inFile(file string) Diagnostics {
+	result := make(Diagnostics, 0, len(d))
+	for _, diagnostic := range d {
+		if diagnostic.File == "" {
+			diagnostic.File = file
+		}
+		result = append(result, diagnostic)
+	}
+
+	return result
+}
+
+var yamlErrorRegexp = regexp.MustCompile(`^yaml: line ([0-9]+): (.*)$`)
+
+func syntaxErrorDiagnostic(err error) Diagnostic {
+	matches := yamlErrorRegexp.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return Diagnostic{Message: fmt.Sprintf("can't parse schema: %v", err)}
+	}
+
+	line, _ := strconv.Atoi(matches[1])
+	return Diagnostic{
+		Position: Position{Line: line, Column: 1},
+		Message:  fmt.Sprintf("can't parse schema: %v", matches[2]),
+	}
+}
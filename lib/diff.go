@@ -0,0 +1,697 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	CompatibleChange ChangeKind = "compatible"
+	BreakingChange   ChangeKind = "breaking"
+)
+
+type Change struct {
+	Kind    ChangeKind `json:"kind"`
+	Path    string     `json:"path"`
+	Message string     `json:"message"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%-10v %v: %v", c.Kind, c.Path, c.Message)
+}
+
+func HasBreakingChanges(changes []Change) bool {
+	for _, change := range changes {
+		if change.Kind == BreakingChange {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DiffServices classifies every difference between two versions of a service
+// from the point of view of a client built against oldService. Types reachable
+// from params are checked as inputs (tightening breaks clients), types reachable
+// from results and error data as outputs (loosening breaks clients, e.g. wider
+// bounds, removed patterns, new enum values or new mappings they can't decode).
+func DiffServices(oldService *Service, newService *Service) []Change {
+	d := serviceDiffer{
+		oldService:  oldService,
+		newService:  newService,
+		inputTypes:  reachableTypes(oldService, true),
+		outputTypes: reachableTypes(oldService, false),
+	}
+
+	d.diffMethods()
+	d.diffErrors()
+	d.diffTypes()
+
+	return d.changes
+}
+
+// CheckVersionBump returns an error when changes contain breaking changes but
+// the version of newService is not a major bump (a minor bump for 0.x versions).
+func CheckVersionBump(oldService *Service, newService *Service, changes []Change) error {
+	if !HasBreakingChanges(changes) {
+		return nil
+	}
+
+	oldVersion, err := parseVersion(oldService.Version)
+	if err != nil {
+		return fmt.Errorf("old schema: %v", err)
+	}
+
+	newVersion, err := parseVersion(newService.Version)
+	if err != nil {
+		return fmt.Errorf("new schema: %v", err)
+	}
+
+	if oldVersion[0] == 0 && newVersion[0] == 0 {
+		if newVersion[1] > oldVersion[1] {
+			return nil
+		}
+		return fmt.Errorf("breaking changes require a minor version bump of a 0.x service, got %v -> %v", oldService.Version, newService.Version)
+	}
+
+	if newVersion[0] > oldVersion[0] {
+		return nil
+	}
+
+	return fmt.Errorf("breaking changes require a major version bump, got %v -> %v", oldService.Version, newService.Version)
+}
+
+func parseVersion(version string) ([3]int, error) {
+	result := [3]int{}
+
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	for index, part := range parts {
+		part = strings.SplitN(part, "-", 2)[0]
+
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return result, fmt.Errorf("invalid version %q", version)
+		}
+		result[index] = number
+	}
+
+	return result, nil
+}
+
+type serviceDiffer struct {
+	oldService  *Service
+	newService  *Service
+	inputTypes  map[string]bool
+	outputTypes map[string]bool
+	changes     []Change
+}
+
+func (d *serviceDiffer) add(kind ChangeKind, path string, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Kind:    kind,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *serviceDiffer) diffMethods() {
+	newMethods := map[MethodName]Method{}
+	for _, method := range d.newService.Methods {
+		newMethods[method.Name] = method
+	}
+
+	oldMethods := map[MethodName]bool{}
+	for _, oldMethod := range d.oldService.Methods {
+		oldMethods[oldMethod.Name] = true
+		path := "methods." + string(oldMethod.Name)
+
+		newMethod, ok := newMethods[oldMethod.Name]
+		if !ok {
+			d.add(BreakingChange, path, "method removed")
+			continue
+		}
+
+		d.diffParams(path+".params", oldMethod.Params, newMethod.Params)
+		if !oldMethod.Result.IsOptional && newMethod.Result.IsOptional {
+			d.add(BreakingChange, path+".result", "result became optional")
+		}
+
+		d.diffTypeInfo(path+".result", oldMethod.Result, newMethod.Result, false, true)
+		d.diffMethodErrors(path+".errors", oldMethod.Errors, newMethod.Errors)
+	}
+
+	for _, newMethod := range d.newService.Methods {
+		if !oldMethods[newMethod.Name] {
+			d.add(CompatibleChange, "methods."+string(newMethod.Name), "method added")
+		}
+	}
+}
+
+func (d *serviceDiffer) diffParams(path string, oldParams []Parameter, newParams []Parameter) {
+	newFields := StructTypeData{}
+	for _, param := range newParams {
+		newFields = append(newFields, Field{Name: FieldName(param.Name), TypeInfo: param.TypeInfo})
+	}
+
+	oldFields := StructTypeData{}
+	for _, param := range oldParams {
+		oldFields = append(oldFields, Field{Name: FieldName(param.Name), TypeInfo: param.TypeInfo})
+	}
+
+	d.diffFields(path, "param", oldFields, newFields, true, false)
+
+	// params may be passed by position in JSON-RPC 2.0, a param inserted before
+	// others shifts them too
+	for oldIndex, oldParam := range oldParams {
+		for newIndex, newParam := range newParams {
+			if newParam.Name == oldParam.Name && newIndex != oldIndex {
+				d.add(BreakingChange, path+"."+string(oldParam.Name), "param moved from position %v to %v", oldIndex+1, newIndex+1)
+			}
+		}
+	}
+}
+
+// diffMethodErrors reports errors a method starts returning as breaking, old
+// clients can't decode an error they don't know. An error that is no longer
+// returned breaks nobody.
+func (d *serviceDiffer) diffMethodErrors(path string, oldErrors []MethodError, newErrors []MethodError) {
+	for _, oldError := range oldErrors {
+		if !hasMethodError(newErrors, oldError.Name) {
+			d.add(CompatibleChange, path+"."+string(oldError.Name), "error removed")
+		}
+	}
+
+	for _, newError := range newErrors {
+		if !hasMethodError(oldErrors, newError.Name) {
+			d.add(BreakingChange, path+"."+string(newError.Name), "error added")
+		}
+	}
+}
+
+func hasMethodError(errors []MethodError, name ErrorName) bool {
+	for _, methodError := range errors {
+		if methodError.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diffErrors compares declared errors by name, an error keeping its code under
+// another name is reported as renamed. Error data is an output.
+func (d *serviceDiffer) diffErrors() {
+	for _, oldError := range d.oldService.Errors {
+		path := "errors." + string(oldError.Name)
+
+		newError, ok := findError(d.newService.Errors, oldError.Name)
+		if !ok {
+			if renamedError, ok := d.findRenamedError(oldError); ok {
+				d.add(BreakingChange, path, "error renamed from %v to %v", oldError.Name, renamedError.Name)
+			} else {
+				d.add(CompatibleChange, path, "error removed")
+			}
+			continue
+		}
+
+		if oldError.Code != newError.Code {
+			d.add(BreakingChange, path, "error code changed from %v to %v", oldError.Code, newError.Code)
+		}
+
+		if oldError.Message != newError.Message {
+			d.add(CompatibleChange, path, "error message changed")
+		}
+
+		d.diffFields(path+".data", "data field", oldError.Data, newError.Data, false, true)
+	}
+
+	for _, newError := range d.newService.Errors {
+		if _, ok := findError(d.oldService.Errors, newError.Name); !ok {
+			d.add(CompatibleChange, "errors."+string(newError.Name), "error added")
+		}
+	}
+}
+
+// findRenamedError returns the new error with the code of oldError, if it
+// didn't exist before.
+func (d *serviceDiffer) findRenamedError(oldError ErrorDefinition) (ErrorDefinition, bool) {
+	for _, definition := range d.newService.Errors {
+		if _, existed := findError(d.oldService.Errors, definition.Name); definition.Code == oldError.Code && !existed {
+			return definition, true
+		}
+	}
+
+	return ErrorDefinition{}, false
+}
+
+func findError(definitions []ErrorDefinition, name ErrorName) (ErrorDefinition, bool) {
+	for _, definition := range definitions {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+
+	return ErrorDefinition{}, false
+}
+
+func (d *serviceDiffer) diffTypes() {
+	d.diffServiceTypes("", d.oldService, d.newService)
+}
+
+// diffServiceTypes diffs the types of oldService and its imports, prefix is the
+// namespace path of oldService from the diffed service, e.g. "common.".
+func (d *serviceDiffer) diffServiceTypes(prefix string, oldService *Service, newService *Service) {
+	for _, oldDefinition := range oldService.Types {
+		name := prefix + string(oldDefinition.Name)
+		path := "types." + name
+		isInput := d.inputTypes[name]
+		isOutput := d.outputTypes[name]
+
+		newData, ok := newService.Types.Get(oldDefinition.Name)
+		if !ok {
+			d.add(BreakingChange, path, "type removed")
+			continue
+		}
+
+		switch oldData := oldDefinition.Data.(type) {
+		case StructTypeData:
+			newStruct, ok := newData.(StructTypeData)
+			if !ok {
+				d.add(BreakingChange, path, "type changed from struct to enum")
+				continue
+			}
+			d.diffFields(path, "field", oldData, newStruct, isInput, isOutput)
+
+		case EnumTypeData:
+			newEnum, ok := newData.(EnumTypeData)
+			if !ok {
+				d.add(BreakingChange, path, "type changed from enum to struct")
+				continue
+			}
+			d.diffEnum(path, oldData, newEnum, isOutput)
+		}
+	}
+
+	for _, newDefinition := range newService.Types {
+		if _, ok := oldService.Types.Get(newDefinition.Name); !ok {
+			d.add(CompatibleChange, "types."+prefix+string(newDefinition.Name), "type added")
+		}
+	}
+
+	for _, oldImport := range oldService.Imports {
+		if oldImport.Service == nil {
+			continue
+		}
+
+		newImportService := &Service{}
+		if newImport, ok := newService.importByNamespace(oldImport.Namespace); ok && newImport.Service != nil {
+			newImportService = newImport.Service
+		}
+
+		d.diffServiceTypes(prefix+oldImport.Namespace+".", oldImport.Service, newImportService)
+	}
+
+	for _, newImport := range newService.Imports {
+		if _, ok := oldService.importByNamespace(newImport.Namespace); ok || newImport.Service == nil {
+			continue
+		}
+
+		d.diffServiceTypes(prefix+newImport.Namespace+".", &Service{}, newImport.Service)
+	}
+}
+
+func (d *serviceDiffer) diffFields(path string, what string, oldFields StructTypeData, newFields StructTypeData, isInput bool, isOutput bool) {
+	for _, oldField := range oldFields {
+		fieldPath := path + "." + string(oldField.Name)
+
+		newField, ok := findField(newFields, oldField.Name)
+		if !ok {
+			d.add(BreakingChange, fieldPath, "%v removed", what)
+			continue
+		}
+
+		if oldField.TypeInfo.IsOptional && !newField.TypeInfo.IsOptional && isInput {
+			d.add(BreakingChange, fieldPath, "%v became required", what)
+		} else if !oldField.TypeInfo.IsOptional && newField.TypeInfo.IsOptional && isOutput {
+			d.add(BreakingChange, fieldPath, "%v became optional", what)
+		} else if oldField.TypeInfo.IsOptional != newField.TypeInfo.IsOptional {
+			d.add(CompatibleChange, fieldPath, "%v optionality changed", what)
+		}
+
+		if oldField.TypeInfo.IsVariable || newField.TypeInfo.IsVariable {
+			d.diffVariableField(fieldPath, oldField.TypeInfo, newField.TypeInfo, isOutput)
+			continue
+		}
+
+		d.diffTypeInfo(fieldPath, oldField.TypeInfo, newField.TypeInfo, isInput, isOutput)
+	}
+
+	for _, newField := range newFields {
+		if _, ok := findField(oldFields, newField.Name); ok {
+			continue
+		}
+
+		fieldPath := path + "." + string(newField.Name)
+		if isInput && !newField.TypeInfo.IsOptional {
+			d.add(BreakingChange, fieldPath, "required %v added", what)
+		} else {
+			d.add(CompatibleChange, fieldPath, "%v added", what)
+		}
+	}
+}
+
+func findField(fields StructTypeData, name FieldName) (Field, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+
+	return Field{}, false
+}
+
+func (d *serviceDiffer) diffTypeInfo(path string, oldTypeInfo TypeInfo, newTypeInfo TypeInfo, isInput bool, isOutput bool) {
+	oldType := describeType(oldTypeInfo)
+	newType := describeType(newTypeInfo)
+
+	if oldTypeInfo.Namespace != newTypeInfo.Namespace || oldTypeInfo.DataType != newTypeInfo.DataType || oldTypeInfo.IsArray != newTypeInfo.IsArray ||
+		oldTypeInfo.IsMap != newTypeInfo.IsMap || oldTypeInfo.KeyType != newTypeInfo.KeyType {
+		d.add(BreakingChange, path, "type changed from %v to %v", oldType, newType)
+		return
+	}
+
+	d.diffConstraints(path, oldTypeInfo, newTypeInfo, isInput, isOutput)
+
+	if oldTypeInfo.Min == newTypeInfo.Min && oldTypeInfo.Max == newTypeInfo.Max &&
+		isSameBound(oldTypeInfo.Minimum, newTypeInfo.Minimum) && isSameBound(oldTypeInfo.Maximum, newTypeInfo.Maximum) {
+		return
+	}
+
+	// loosening is tightening the other way round
+	isTightened := areBoundsTightened(oldTypeInfo, newTypeInfo)
+	isLoosened := areBoundsTightened(newTypeInfo, oldTypeInfo)
+
+	switch {
+	case isTightened && isInput:
+		d.add(BreakingChange, path, "bounds tightened from %v to %v", oldType, newType)
+	case isLoosened && isOutput:
+		d.add(BreakingChange, path, "bounds loosened from %v to %v", oldType, newType)
+	default:
+		d.add(CompatibleChange, path, "bounds changed from %v to %v", oldType, newType)
+	}
+}
+
+// areBoundsTightened reports whether newTypeInfo rejects a length or a value
+// oldTypeInfo accepts.
+func areBoundsTightened(oldTypeInfo TypeInfo, newTypeInfo TypeInfo) bool {
+	return newTypeInfo.Min > oldTypeInfo.Min ||
+		(newTypeInfo.Max >= 0 && (oldTypeInfo.Max < 0 || newTypeInfo.Max < oldTypeInfo.Max)) ||
+		isBoundTightened(oldTypeInfo.Minimum, newTypeInfo.Minimum, 1) ||
+		isBoundTightened(oldTypeInfo.Maximum, newTypeInfo.Maximum, -1)
+}
+
+// diffConstraints treats any other pattern as both tighter and looser, a
+// multipleOf is looser when it divides the old one.
+func (d *serviceDiffer) diffConstraints(path string, oldTypeInfo TypeInfo, newTypeInfo TypeInfo, isInput bool, isOutput bool) {
+	if oldTypeInfo.Pattern != newTypeInfo.Pattern {
+		isTightened := newTypeInfo.Pattern != ""
+		isLoosened := oldTypeInfo.Pattern != ""
+
+		kind := CompatibleChange
+		if (isTightened && isInput) || (isLoosened && isOutput) {
+			kind = BreakingChange
+		}
+		d.add(kind, path, "pattern changed from %q to %q", oldTypeInfo.Pattern, newTypeInfo.Pattern)
+	}
+
+	if oldTypeInfo.MultipleOf != newTypeInfo.MultipleOf {
+		isTightened := !isMultipleOfLooser(oldTypeInfo.MultipleOf, newTypeInfo.MultipleOf)
+		isLoosened := !isMultipleOfLooser(newTypeInfo.MultipleOf, oldTypeInfo.MultipleOf)
+
+		kind := CompatibleChange
+		if (isTightened && isInput) || (isLoosened && isOutput) {
+			kind = BreakingChange
+		}
+		d.add(kind, path, "multipleOf changed from %v to %v", oldTypeInfo.MultipleOf, newTypeInfo.MultipleOf)
+	}
+}
+
+// isMultipleOfLooser reports whether newMultipleOf accepts every value
+// oldMultipleOf accepts, 0 means no constraint.
+func isMultipleOfLooser(oldMultipleOf int64, newMultipleOf int64) bool {
+	return newMultipleOf == 0 || (oldMultipleOf != 0 && oldMultipleOf%newMultipleOf == 0)
+}
+
+func isSameBound(oldBound *Bound, newBound *Bound) bool {
+	if oldBound == nil || newBound == nil {
+		return oldBound == newBound
+	}
+
+	return *oldBound == *newBound
+}
+
+// isBoundTightened reports whether newBound accepts fewer values than
+// oldBound. direction is 1 for minimums and -1 for maximums.
+func isBoundTightened(oldBound *Bound, newBound *Bound, direction int64) bool {
+	if newBound == nil {
+		return false
+	}
+
+	if oldBound == nil {
+		return true
+	}
+
+	if newBound.Value != oldBound.Value {
+		return (newBound.Value-oldBound.Value)*direction > 0
+	}
+
+	return newBound.Exclusive && !oldBound.Exclusive
+}
+
+func (d *serviceDiffer) diffVariableField(path string, oldTypeInfo TypeInfo, newTypeInfo TypeInfo, isOutput bool) {
+	if oldTypeInfo.IsVariable != newTypeInfo.IsVariable {
+		d.add(BreakingChange, path, "type changed from %v to %v", describeType(oldTypeInfo), describeType(newTypeInfo))
+		return
+	}
+
+	if oldTypeInfo.MapField != newTypeInfo.MapField {
+		d.add(BreakingChange, path, "mapField changed from %v to %v", oldTypeInfo.MapField, newTypeInfo.MapField)
+	}
+
+	for _, oldCase := range oldTypeInfo.Mapping {
+		casePath := fmt.Sprintf("%v[%v]", path, oldCase.Value)
+
+		newCase, ok := findMappingCase(newTypeInfo.Mapping, oldCase.Value)
+		if !ok {
+			d.add(BreakingChange, casePath, "mapping removed")
+			continue
+		}
+
+		if describeType(oldCase.TypeInfo) != describeType(newCase.TypeInfo) {
+			d.add(BreakingChange, casePath, "mapping changed from %v to %v", describeType(oldCase.TypeInfo), describeType(newCase.TypeInfo))
+		}
+	}
+
+	for _, newCase := range newTypeInfo.Mapping {
+		if _, ok := findMappingCase(oldTypeInfo.Mapping, newCase.Value); ok {
+			continue
+		}
+
+		// clients reject values of mapField they don't know
+		kind := CompatibleChange
+		if isOutput {
+			kind = BreakingChange
+		}
+		d.add(kind, fmt.Sprintf("%v[%v]", path, newCase.Value), "mapping added")
+	}
+}
+
+func findMappingCase(mapping []MappingCase, value string) (MappingCase, bool) {
+	for _, mappingCase := range mapping {
+		if mappingCase.Value == value {
+			return mappingCase, true
+		}
+	}
+
+	return MappingCase{}, false
+}
+
+func (d *serviceDiffer) diffEnum(path string, oldEnum EnumTypeData, newEnum EnumTypeData, isOutput bool) {
+	if oldEnum.Type != newEnum.Type {
+		d.add(BreakingChange, path, "enum type changed from %v to %v", oldEnum.Type, newEnum.Type)
+		return
+	}
+
+	// values are matched by what is sent, names only exist in generated code
+	for _, oldValue := range oldEnum.Values {
+		valuePath := path + ".values." + oldValue.Name
+
+		newValue, ok := findEnumValue(newEnum, getEnumWireValue(oldEnum, oldValue))
+		if !ok {
+			d.add(BreakingChange, valuePath, "enum value %v removed", getEnumWireValue(oldEnum, oldValue))
+			continue
+		}
+
+		if newValue.Name != oldValue.Name {
+			d.add(CompatibleChange, valuePath, "enum constant renamed from %v to %v", oldValue.Name, newValue.Name)
+		}
+	}
+
+	for _, newValue := range newEnum.Values {
+		if _, ok := findEnumValue(oldEnum, getEnumWireValue(newEnum, newValue)); ok {
+			continue
+		}
+
+		// generated clients reject values they don't know
+		kind := CompatibleChange
+		if isOutput {
+			kind = BreakingChange
+		}
+		d.add(kind, path+".values."+newValue.Name, "enum value %v added", getEnumWireValue(newEnum, newValue))
+	}
+}
+
+// getEnumWireValue returns value as it is sent, int values are normalized.
+func getEnumWireValue(enum EnumTypeData, value EnumValue) string {
+	if enum.Type == "int" {
+		return strconv.Itoa(value.IntegerValue)
+	}
+
+	return value.StringValue
+}
+
+func findEnumValue(enum EnumTypeData, wireValue string) (EnumValue, bool) {
+	for _, value := range enum.Values {
+		if getEnumWireValue(enum, value) == wireValue {
+			return value, true
+		}
+	}
+
+	return EnumValue{}, false
+}
+
+func describeType(typeInfo TypeInfo) string {
+	if typeInfo.IsVariable {
+		return "variable(" + string(typeInfo.MapField) + ")"
+	}
+
+	result := ""
+	if typeInfo.IsArray {
+		result += "[]"
+	}
+	if typeInfo.IsMap {
+		result += "map[" + typeInfo.KeyType + "]"
+	}
+	if typeInfo.Namespace != "" {
+		result += typeInfo.Namespace + "."
+	}
+	result += typeInfo.DataType
+
+	if typeInfo.Minimum != nil || typeInfo.Maximum != nil {
+		result += describeRange(typeInfo.Minimum, typeInfo.Maximum)
+	}
+
+	if typeInfo.Max >= 0 && typeInfo.Min != typeInfo.Max {
+		result += fmt.Sprintf("(%v,%v)", typeInfo.Min, typeInfo.Max)
+	} else if typeInfo.Max >= 0 {
+		result += fmt.Sprintf("(%v)", typeInfo.Min)
+	}
+
+	if typeInfo.IsOptional {
+		result += "?"
+	}
+
+	return result
+}
+
+// reachableTypes collects the types used, directly or through other types,
+// by method params (inputs) or by method results and error data. Imported types are keyed by
+// their namespace path like "common.Money".
+func reachableTypes(service *Service, inputs bool) map[string]bool {
+	result := map[string]bool{}
+
+	var visit func(service *Service, prefix string, typeInfo TypeInfo)
+	visit = func(service *Service, prefix string, typeInfo TypeInfo) {
+		for _, mappingCase := range typeInfo.Mapping {
+			visit(service, prefix, mappingCase.TypeInfo)
+		}
+
+		if !typeInfo.IsCustomType {
+			return
+		}
+
+		if typeInfo.Namespace != "" {
+			schemaImport, ok := service.importByNamespace(typeInfo.Namespace)
+			if !ok || schemaImport.Service == nil {
+				return
+			}
+
+			service = schemaImport.Service
+			prefix += typeInfo.Namespace + "."
+		}
+
+		name := prefix + typeInfo.DataType
+		if result[name] {
+			return
+		}
+
+		data, ok := service.Types.Get(TypeName(typeInfo.DataType))
+		if !ok {
+			return
+		}
+		result[name] = true
+
+		if structData, ok := data.(StructTypeData); ok {
+			for _, field := range structData {
+				visit(service, prefix, field.TypeInfo)
+			}
+		}
+	}
+
+	if !inputs {
+		for _, definition := range service.Errors {
+			for _, field := range definition.Data {
+				visit(service, "", field.TypeInfo)
+			}
+		}
+	}
+
+	for _, method := range service.Methods {
+		if !inputs {
+			visit(service, "", method.Result)
+			continue
+		}
+
+		for _, param := range method.Params {
+			visit(service, "", param.TypeInfo)
+		}
+	}
+
+	return result
+}
+
+func describeRange(minimum *Bound, maximum *Bound) string {
+	result := "["
+	if minimum != nil {
+		if minimum.Exclusive {
+			result = "("
+		}
+		result += strconv.FormatInt(minimum.Value, 10)
+	}
+
+	result += ".."
+
+	if maximum == nil {
+		return result + "]"
+	}
+
+	result += strconv.FormatInt(maximum.Value, 10)
+	if maximum.Exclusive {
+		return result + ")"
+	}
+
+	return result + "]"
+}
@@ -1,36 +1,185 @@
 package lib
 
 import (
+	"encoding/json"
 	"fmt"
-	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 )
 
-func Build(serviceSchemaPath string, outputPath string) error {
-	rawSchema, err := ioutil.ReadFile(serviceSchemaPath)
+func ValidateSchema(serviceSchemaPath string) Diagnostics {
+	_, err := LoadService(serviceSchemaPath)
+	if err == nil {
+		return nil
+	}
+
+	if diagnostics, ok := err.(Diagnostics); ok {
+		return diagnostics
+	}
+
+	return Diagnostics{{File: serviceSchemaPath, Message: err.Error()}}
+}
+
+type BuildOptions struct {
+	// Context makes handlers and the executor take a context.Context.
+	Context bool
+	// Target is the generated output: "go" (default), "typescript", "python",
+	// "openrpc", "jsonschema", "protobuf" or "docs".
+	Target string
+}
+
+func Build(serviceSchemaPath string, outputPath string, options BuildOptions) error {
+	service, err := LoadService(serviceSchemaPath)
+	if err != nil {
+		return err
+	}
+
+	switch options.Target {
+	case "", "go":
+		err = buildGo(service, outputPath, options)
+	case "typescript":
+		err = buildTypeScript(service, outputPath)
+	case "python":
+		err = buildPython(service, outputPath)
+	case "openrpc":
+		err = buildOpenRpc(service, outputPath)
+	case "jsonschema":
+		err = buildJsonSchema(service, outputPath)
+	case "protobuf":
+		err = buildProtobuf(service, outputPath)
+	case "docs":
+		err = buildDocsTarget(service, outputPath)
+	default:
+		err = fmt.Errorf("unknown target: %v", options.Target)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Success!")
+	return nil
+}
+
+func buildTypeScript(service *Service, outputPath string) error {
+	fileText, err := buildTypeScriptFile(service)
 	if err != nil {
 		return err
 	}
 
-	service := Service{}
-	err = yaml.Unmarshal(rawSchema, &service)
+	return ioutil.WriteFile(filepath.Join(outputPath, "index.ts"), []byte(fileText), 0777)
+}
 
+func buildPython(service *Service, outputPath string) error {
+	fileText, err := buildPythonFile(service)
 	if err != nil {
-		return fmt.Errorf("can't parse schema: %v/n", err)
+		return err
 	}
 
-	typesFileText, err := buildTypesFile(&service)
+	return ioutil.WriteFile(filepath.Join(outputPath, "client.py"), []byte(fileText), 0777)
+}
+
+func buildOpenRpc(service *Service, outputPath string) error {
+	fileText, err := buildOpenRpcFile(service)
 	if err != nil {
 		return err
 	}
 
-	handlerInterfaceFileText, err := buildHandlerInterfaceFile(&service)
+	return ioutil.WriteFile(filepath.Join(outputPath, "openrpc.json"), []byte(fileText), 0777)
+}
+
+func buildJsonSchema(service *Service, outputPath string) error {
+	files, err := buildJsonSchemaFiles(service)
 	if err != nil {
 		return err
 	}
 
-	executorFileText, err := buildExecutorFile(&service)
+	for fileName, fileText := range files {
+		err = ioutil.WriteFile(filepath.Join(outputPath, fileName), []byte(fileText), 0777)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func buildDocsTarget(service *Service, outputPath string) error {
+	files, err := buildDocsFiles(service)
+	if err != nil {
+		return err
+	}
+
+	for fileName, fileText := range files {
+		err = ioutil.WriteFile(filepath.Join(outputPath, fileName), []byte(fileText), 0777)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildProtobuf writes the proto file along with its lock, an existing lock
+// keeps the numbers of the previous build.
+func buildProtobuf(service *Service, outputPath string) error {
+	fileName := service.Package + ".proto"
+	lockPath := filepath.Join(outputPath, fileName+".lock")
+
+	lock := newProtobufLock()
+	packedLock, err := ioutil.ReadFile(lockPath)
+	if err == nil {
+		lock, err = parseProtobufLock(packedLock)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	fileText, err := buildProtobufFile(service, lock)
+	if err != nil {
+		return err
+	}
+
+	packedLock, err = json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(outputPath, fileName), []byte(fileText), 0777)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(lockPath, append(packedLock, '\n'), 0777)
+}
+
+func buildGo(service *Service, outputPath string, options BuildOptions) error {
+	if !options.Context {
+		for _, method := range service.Methods {
+			if method.Timeout > 0 {
+				return fmt.Errorf("method %v has a timeout, timeouts require the context option", method.Name)
+			}
+		}
+	}
+
+	typesFileText, err := buildTypesFile(service)
+	if err != nil {
+		return err
+	}
+
+	handlerInterfaceFileText, err := buildHandlerInterfaceFile(service, options)
+	if err != nil {
+		return err
+	}
+
+	executorFileText, err := buildExecutorFile(service, options)
+	if err != nil {
+		return err
+	}
+
+	clientFileText, err := buildClientFile(service)
 	if err != nil {
 		return err
 	}
@@ -50,6 +199,5 @@ func Build(serviceSchemaPath string, outputPath string) error {
 		return err
 	}
 
-	fmt.Println("Success!")
-	return nil
+	return ioutil.WriteFile(filepath.Join(outputPath, "client.go"), []byte(clientFileText), 0777)
 }
@@ -0,0 +1,854 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var importedFormats = map[string]string{
+	"uuid":     "uuid",
+	"email":    "email",
+	"uri":      "url",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+	"date":     "date",
+}
+
+// importedPatterns are the patterns standing for types of the schema, the
+// reverse of jsonSchemaPatterns.
+var importedPatterns = map[string]string{
+	phonePattern:             "phone",
+	"^[0-9a-fA-F]+$":         "hex",
+	"^[A-Za-z0-9+/]*={0,2}$": "base64",
+}
+
+// unsupportedKeywords are validation keywords without an equivalent in the
+// schema, annotations like title or examples are skipped silently.
+var unsupportedKeywords = []string{
+	"const", "not", "allOf", "if", "then", "else", "dependentRequired", "dependentSchemas",
+	"dependencies", "uniqueItems", "contains", "minContains", "maxContains", "prefixItems",
+	"patternProperties", "unevaluatedProperties", "unevaluatedItems", "contentEncoding",
+	"contentMediaType",
+}
+
+var nonIdentifierRegexp = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+type jsonSchemaImporter struct {
+	directory   string
+	definitions map[string]*yaml.Node
+	typeNames   map[string]string
+	converting  map[string]bool
+	types       *yaml.Node
+	diagnostics Diagnostics
+}
+
+// ImportJsonSchema converts the object and enum definitions of the JSON Schema
+// document at path into the types section of a service schema. Constructs
+// without an equivalent are reported as diagnostics and left out of the types.
+func ImportJsonSchema(path string) ([]byte, Diagnostics, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := parseJsonSchema(content, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	importer := &jsonSchemaImporter{
+		directory:   filepath.Dir(path),
+		definitions: map[string]*yaml.Node{},
+		typeNames:   map[string]string{},
+		converting:  map[string]bool{},
+		types:       &yaml.Node{Kind: yaml.MappingNode},
+	}
+
+	definitionRefs := []string{}
+	if isTypeDefinition(root) {
+		importer.definitions["#"] = root
+		importer.typeNames["#"] = importer.typeName(documentName(root, path))
+		definitionRefs = append(definitionRefs, "#")
+	}
+
+	for _, section := range []string{"$defs", "definitions"} {
+		definitions := lookupNode(root, section)
+		if definitions == nil || definitions.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for index := 0; index+1 < len(definitions.Content); index += 2 {
+			name := definitions.Content[index].Value
+			ref := "#/" + section + "/" + name
+
+			importer.definitions[ref] = definitions.Content[index+1]
+			if isTypeDefinition(definitions.Content[index+1]) {
+				importer.typeNames[ref] = importer.typeName(name)
+				definitionRefs = append(definitionRefs, ref)
+			}
+		}
+	}
+
+	for _, ref := range definitionRefs {
+		importer.addType(importer.typeNames[ref], importer.definitions[ref], ref)
+	}
+
+	output := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(output)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "types"},
+			importer.types,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return output.Bytes(), importer.diagnostics.inFile(path), nil
+}
+
+func parseJsonSchema(content []byte, path string) (*yaml.Node, error) {
+	// JSON is YAML, and yaml.Node keeps the order of the properties
+	var document yaml.Node
+	err := yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse %v: %v", path, err)
+	}
+
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%v is not a JSON Schema object", path)
+	}
+
+	return document.Content[0], nil
+}
+
+// documentName is the name of the type defined by a whole document.
+func documentName(node *yaml.Node, path string) string {
+	if title := lookupNode(node, "title"); title != nil {
+		return title.Value
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".json"), ".schema")
+}
+
+func lookupNode(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		if node.Content[index].Value == key {
+			return node.Content[index+1]
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes returns the types a schema allows, "type" may be a string or
+// a list of them.
+func schemaTypes(node *yaml.Node) []string {
+	typeNode := lookupNode(node, "type")
+	if typeNode == nil {
+		return nil
+	}
+
+	if typeNode.Kind == yaml.SequenceNode {
+		types := []string{}
+		for _, item := range typeNode.Content {
+			types = append(types, item.Value)
+		}
+		return types
+	}
+
+	return []string{typeNode.Value}
+}
+
+// isTypeDefinition reports whether a definition becomes a type of the
+// schema, other definitions are inlined where they are referenced.
+func isTypeDefinition(node *yaml.Node) bool {
+	if lookupNode(node, "enum") != nil {
+		return true
+	}
+
+	return lookupNode(node, "properties") != nil || lookupNode(node, "oneOf") != nil && lookupNode(node, "discriminator") != nil
+}
+
+func (i *jsonSchemaImporter) report(node *yaml.Node, pointer string, format string, args ...interface{}) {
+	i.diagnostics.add(positionOf(node), "%v: %v", pointer, fmt.Sprintf(format, args...))
+}
+
+// typeName returns a unique type name for a definition or inline schema.
+func (i *jsonSchemaImporter) typeName(name string) string {
+	parts := nonIdentifierRegexp.Split(name, -1)
+	for index := range parts {
+		parts[index] = strings.Title(parts[index])
+	}
+
+	typeName := strings.Join(parts, "")
+	if typeName == "" || !isIdentifier(typeName) {
+		typeName = "Type" + typeName
+	}
+
+	used := map[string]bool{}
+	for _, existing := range i.typeNames {
+		used[existing] = true
+	}
+
+	uniqueName := typeName
+	for index := 2; used[uniqueName]; index++ {
+		uniqueName = typeName + strconv.Itoa(index)
+	}
+
+	return uniqueName
+}
+
+func (i *jsonSchemaImporter) addType(name string, node *yaml.Node, pointer string) {
+	i.reportUnsupported(node, pointer)
+
+	if lookupNode(node, "enum") != nil {
+		if enum := i.enum(node, pointer); enum != nil {
+			i.appendType(name+"(enum)", enum)
+		}
+		return
+	}
+
+	i.appendType(name, i.structData(name, node, pointer))
+}
+
+func (i *jsonSchemaImporter) appendType(key string, value *yaml.Node) {
+	i.types.Content = append(i.types.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+func (i *jsonSchemaImporter) reportUnsupported(node *yaml.Node, pointer string) {
+	for _, keyword := range unsupportedKeywords {
+		if value := lookupNode(node, keyword); value != nil {
+			i.report(value, pointer, "%v is not supported, ignored", keyword)
+		}
+	}
+}
+
+func (i *jsonSchemaImporter) enum(node *yaml.Node, pointer string) *yaml.Node {
+	values := lookupNode(node, "enum")
+	if values.Kind != yaml.SequenceNode {
+		i.report(values, pointer, "enum must be a list")
+		return nil
+	}
+
+	enumType := "string"
+	for _, value := range values.Content {
+		if value.Tag == "!!int" {
+			enumType = "int"
+		}
+	}
+
+	valuesNode := &yaml.Node{Kind: yaml.MappingNode}
+	usedNames := map[string]bool{}
+	for _, value := range values.Content {
+		switch {
+		case value.Tag == "!!null":
+			continue
+		case enumType == "int" && value.Tag != "!!int", enumType == "string" && value.Tag != "!!str":
+			i.report(value, pointer, "enum value %v is not of type %v, dropped", value.Value, enumType)
+			continue
+		}
+
+		name := enumValueName(value.Value, enumType)
+		uniqueName := name
+		for index := 2; usedNames[uniqueName]; index++ {
+			uniqueName = name + strconv.Itoa(index)
+		}
+		usedNames[uniqueName] = true
+
+		valueNode := &yaml.Node{Kind: yaml.ScalarNode, Value: value.Value}
+		if enumType == "string" {
+			valueNode.Style = yaml.DoubleQuotedStyle
+		}
+
+		valuesNode.Content = append(valuesNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: uniqueName}, valueNode)
+	}
+
+	return &yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "type"},
+			{Kind: yaml.ScalarNode, Value: enumType},
+			{Kind: yaml.ScalarNode, Value: "values"},
+			valuesNode,
+		},
+	}
+}
+
+func enumValueName(value string, enumType string) string {
+	if enumType == "int" {
+		return "value" + strings.Replace(value, "-", "Minus", 1)
+	}
+
+	parts := nonIdentifierRegexp.Split(value, -1)
+	name := ""
+	for _, part := range parts {
+		if name == "" {
+			name = part
+		} else {
+			name += strings.Title(part)
+		}
+	}
+
+	if name == "" || !isIdentifier(name) {
+		return "value" + strings.Title(name)
+	}
+
+	return name
+}
+
+func (i *jsonSchemaImporter) structData(name string, node *yaml.Node, pointer string) *yaml.Node {
+	fields := &yaml.Node{Kind: yaml.MappingNode}
+
+	required := map[string]bool{}
+	if requiredNode := lookupNode(node, "required"); requiredNode != nil {
+		for _, item := range requiredNode.Content {
+			required[item.Value] = true
+		}
+	}
+
+	if additional := lookupNode(node, "additionalProperties"); additional != nil && additional.Value != "false" {
+		i.report(additional, pointer, "additionalProperties of an object with properties is not supported, ignored")
+	}
+
+	properties := lookupNode(node, "properties")
+	if properties != nil && properties.Kind == yaml.MappingNode {
+		for index := 0; index+1 < len(properties.Content); index += 2 {
+			fieldName := properties.Content[index].Value
+			fieldPointer := pointer + "/properties/" + fieldName
+
+			if !isIdentifier(fieldName) {
+				i.report(properties.Content[index], fieldPointer, "property name %q is not an identifier, dropped", fieldName)
+				continue
+			}
+
+			value, ok := i.typeExpression(name+strings.Title(fieldName), properties.Content[index+1], fieldPointer, !required[fieldName])
+			if !ok {
+				continue
+			}
+
+			fields.Content = append(fields.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: fieldName}, value)
+		}
+	}
+
+	if oneOf := lookupNode(node, "oneOf"); oneOf != nil {
+		i.variableField(fields, node, oneOf, pointer, required)
+	} else if anyOf := lookupNode(node, "anyOf"); anyOf != nil {
+		i.report(anyOf, pointer, "anyOf of an object is not supported, ignored")
+	}
+
+	return fields
+}
+
+// variableField converts the oneOf of an object discriminated by one of its
+// properties, in the form written by the jsonschema target: every variant
+// sets the discriminator to a const and one other property to its type.
+func (i *jsonSchemaImporter) variableField(fields *yaml.Node, node *yaml.Node, oneOf *yaml.Node, pointer string, required map[string]bool) {
+	discriminator := lookupNode(lookupNode(node, "discriminator"), "propertyName")
+	if discriminator == nil {
+		i.report(oneOf, pointer, "oneOf without a discriminator is not supported, ignored")
+		return
+	}
+
+	mapField := discriminator.Value
+	if lookupNode(fields, mapField) == nil {
+		i.report(discriminator, pointer, "discriminator %q is not a property of the object, oneOf ignored", mapField)
+		return
+	}
+
+	fieldName := ""
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for index, variant := range oneOf.Content {
+		variantPointer := fmt.Sprintf("%v/oneOf/%v", pointer, index)
+
+		variantProperties := lookupNode(variant, "properties")
+		if variantProperties == nil || len(variantProperties.Content) != 4 {
+			i.report(variant, variantPointer, "variant must have exactly the discriminator and one other property, oneOf ignored")
+			return
+		}
+
+		value := lookupNode(lookupNode(variantProperties, mapField), "const")
+		if value == nil {
+			i.report(variant, variantPointer, "variant has no const value of %v, oneOf ignored", mapField)
+			return
+		}
+
+		for propertyIndex := 0; propertyIndex < len(variantProperties.Content); propertyIndex += 2 {
+			name := variantProperties.Content[propertyIndex].Value
+			if name == mapField {
+				continue
+			}
+
+			if fieldName != "" && fieldName != name {
+				i.report(variant, variantPointer, "variants set different properties (%v and %v), oneOf ignored", fieldName, name)
+				return
+			}
+			fieldName = name
+
+			typeNode, ok := i.typeExpression(strings.Title(name)+strings.Title(enumValueName(value.Value, "string")), variantProperties.Content[propertyIndex+1], variantPointer+"/properties/"+name, false)
+			if !ok {
+				return
+			}
+
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: value.Value, Style: yaml.DoubleQuotedStyle}, typeNode)
+		}
+	}
+
+	if fieldName == "" {
+		return
+	}
+
+	if !required[fieldName] {
+		i.report(oneOf, pointer, "variable field %v is optional, imported as required", fieldName)
+	}
+
+	fields.Content = append(fields.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: fieldName + "?"},
+		&yaml.Node{
+			Kind: yaml.MappingNode,
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "mapField"},
+				{Kind: yaml.ScalarNode, Value: mapField},
+				{Kind: yaml.ScalarNode, Value: "mapping"},
+				mapping,
+			},
+		},
+	)
+}
+
+// importedType is a type expression with the constraints of its long form.
+type importedType struct {
+	expression string
+	pattern    string
+	multipleOf string
+}
+
+func (t importedType) node(optional bool) *yaml.Node {
+	expression := t.expression
+	if optional {
+		expression += "?"
+	}
+
+	if t.pattern == "" && t.multipleOf == "" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: expression, Style: yaml.DoubleQuotedStyle}
+	}
+
+	longForm := &yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "type"},
+			{Kind: yaml.ScalarNode, Value: expression, Style: yaml.DoubleQuotedStyle},
+		},
+	}
+
+	if t.pattern != "" {
+		longForm.Content = append(longForm.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "pattern"}, &yaml.Node{Kind: yaml.ScalarNode, Value: t.pattern, Style: yaml.DoubleQuotedStyle})
+	}
+
+	if t.multipleOf != "" {
+		longForm.Content = append(longForm.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "multipleOf"}, &yaml.Node{Kind: yaml.ScalarNode, Value: t.multipleOf})
+	}
+
+	return longForm
+}
+
+// typeExpression converts the schema of a field, inline objects and enums
+// become types named inlineName.
+func (i *jsonSchemaImporter) typeExpression(inlineName string, node *yaml.Node, pointer string, optional bool) (*yaml.Node, bool) {
+	node, nullable := i.nonNullSchema(node, pointer)
+	if node == nil {
+		return nil, false
+	}
+
+	result, ok := i.convert(inlineName, node, pointer)
+	if !ok {
+		return nil, false
+	}
+
+	return result.node(optional || nullable), true
+}
+
+// nonNullSchema strips null from the types a schema allows, a nullable field
+// becomes optional.
+func (i *jsonSchemaImporter) nonNullSchema(node *yaml.Node, pointer string) (*yaml.Node, bool) {
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		alternatives := lookupNode(node, keyword)
+		if alternatives == nil || lookupNode(node, "discriminator") != nil {
+			continue
+		}
+
+		schemas := []*yaml.Node{}
+		nullable := false
+		for _, alternative := range alternatives.Content {
+			types := schemaTypes(alternative)
+			if len(types) == 1 && types[0] == "null" {
+				nullable = true
+				continue
+			}
+			schemas = append(schemas, alternative)
+		}
+
+		if len(schemas) != 1 {
+			i.report(alternatives, pointer, "%v of several schemas is not supported, dropped", keyword)
+			return nil, false
+		}
+
+		return schemas[0], nullable
+	}
+
+	types := schemaTypes(node)
+	if len(types) < 2 {
+		return node, false
+	}
+
+	nonNullTypes := []string{}
+	nullable := false
+	for _, typeName := range types {
+		if typeName == "null" {
+			nullable = true
+			continue
+		}
+		nonNullTypes = append(nonNullTypes, typeName)
+	}
+
+	if len(nonNullTypes) != 1 {
+		i.report(lookupNode(node, "type"), pointer, "several types (%v) are not supported, dropped", strings.Join(nonNullTypes, ", "))
+		return nil, false
+	}
+
+	// a copy of the schema with a single type
+	stripped := *node
+	stripped.Content = []*yaml.Node{}
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		value := node.Content[index+1]
+		if node.Content[index].Value == "type" {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: nonNullTypes[0]}
+		}
+		stripped.Content = append(stripped.Content, node.Content[index], value)
+	}
+
+	return &stripped, nullable
+}
+
+func (i *jsonSchemaImporter) convert(inlineName string, node *yaml.Node, pointer string) (importedType, bool) {
+	if ref := lookupNode(node, "$ref"); ref != nil {
+		return i.reference(ref, pointer)
+	}
+
+	if isTypeDefinition(node) {
+		name := i.typeName(inlineName)
+		i.typeNames[pointer] = name
+		i.addType(name, node, pointer)
+		return importedType{expression: name}, true
+	}
+
+	i.reportUnsupported(node, pointer)
+
+	types := schemaTypes(node)
+	if len(types) == 0 {
+		i.report(node, pointer, "schema without a type is not supported, dropped")
+		return importedType{}, false
+	}
+
+	switch types[0] {
+	case "string":
+		return i.stringType(node, pointer), true
+	case "integer":
+		return i.integerType(node, pointer), true
+	case "boolean":
+		return importedType{expression: "boolean"}, true
+	case "array":
+		return i.arrayType(inlineName, node, pointer)
+	case "object":
+		return i.mapType(inlineName, node, pointer)
+	}
+
+	i.report(lookupNode(node, "type"), pointer, "type %v is not supported, dropped", types[0])
+	return importedType{}, false
+}
+
+func (i *jsonSchemaImporter) reference(ref *yaml.Node, pointer string) (importedType, bool) {
+	if name, ok := i.typeNames[ref.Value]; ok {
+		return importedType{expression: name}, true
+	}
+
+	definition, ok := i.definitions[ref.Value]
+	if !ok && !strings.Contains(ref.Value, "#") {
+		return i.externalReference(ref, pointer)
+	}
+
+	if !ok {
+		i.report(ref, pointer, "reference %v is not a definition of this document, dropped", ref.Value)
+		return importedType{}, false
+	}
+
+	if i.converting[ref.Value] {
+		i.report(ref, pointer, "recursive reference %v is not supported, dropped", ref.Value)
+		return importedType{}, false
+	}
+
+	// definitions of simple types are inlined
+	i.converting[ref.Value] = true
+	defer delete(i.converting, ref.Value)
+
+	definition, _ = i.nonNullSchema(definition, ref.Value)
+	if definition == nil {
+		return importedType{}, false
+	}
+
+	return i.convert(ref.Value[strings.LastIndex(ref.Value, "/")+1:], definition, ref.Value)
+}
+
+// externalReference imports a whole document referenced by a relative path,
+// as the jsonschema target writes them.
+func (i *jsonSchemaImporter) externalReference(ref *yaml.Node, pointer string) (importedType, bool) {
+	path := filepath.Join(i.directory, filepath.FromSlash(ref.Value))
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		i.report(ref, pointer, "can't read reference %v: %v, dropped", ref.Value, err)
+		return importedType{}, false
+	}
+
+	document, err := parseJsonSchema(content, path)
+	if err != nil {
+		i.report(ref, pointer, "%v, dropped", err)
+		return importedType{}, false
+	}
+
+	i.definitions[ref.Value] = document
+	if isTypeDefinition(document) {
+		i.typeNames[ref.Value] = i.typeName(documentName(document, path))
+		i.addType(i.typeNames[ref.Value], document, ref.Value)
+	}
+
+	return i.reference(ref, pointer)
+}
+
+func (i *jsonSchemaImporter) stringType(node *yaml.Node, pointer string) importedType {
+	result := importedType{expression: "string"}
+
+	if format := lookupNode(node, "format"); format != nil {
+		if typeName, ok := importedFormats[format.Value]; ok {
+			result.expression = typeName
+		} else {
+			i.report(format, pointer, "format %v is not supported, imported as string", format.Value)
+		}
+	}
+
+	if pattern := lookupNode(node, "pattern"); pattern != nil {
+		if typeName, ok := importedPatterns[pattern.Value]; ok && result.expression == "string" {
+			result.expression = typeName
+		} else {
+			result.pattern = pattern.Value
+		}
+	}
+
+	minLength := lookupNode(node, "minLength")
+	maxLength := lookupNode(node, "maxLength")
+	switch {
+	case maxLength != nil && minLength != nil:
+		result.expression += fmt.Sprintf("(%v,%v)", minLength.Value, maxLength.Value)
+	case maxLength != nil:
+		result.expression += fmt.Sprintf("(0,%v)", maxLength.Value)
+	case minLength != nil:
+		i.report(minLength, pointer, "minLength without maxLength is not supported, ignored")
+	}
+
+	return result
+}
+
+func (i *jsonSchemaImporter) integerType(node *yaml.Node, pointer string) importedType {
+	result := importedType{expression: "int"}
+	if format := lookupNode(node, "format"); format != nil && format.Value == "int64" {
+		result.expression = "int64"
+	}
+
+	minimum, minimumOpen := "", "["
+	maximum, maximumClose := "", "]"
+
+	if value := lookupNode(node, "minimum"); value != nil {
+		minimum = value.Value
+	}
+	if value := lookupNode(node, "maximum"); value != nil {
+		maximum = value.Value
+	}
+
+	// numeric since draft 6, a flag of minimum and maximum before
+	if value := lookupNode(node, "exclusiveMinimum"); value != nil {
+		switch value.Value {
+		case "true":
+			minimumOpen = "("
+		case "false":
+		default:
+			minimum, minimumOpen = value.Value, "("
+		}
+	}
+	if value := lookupNode(node, "exclusiveMaximum"); value != nil {
+		switch value.Value {
+		case "true":
+			maximumClose = ")"
+		case "false":
+		default:
+			maximum, maximumClose = value.Value, ")"
+		}
+	}
+
+	for _, bound := range []*string{&minimum, &maximum} {
+		if *bound == "" {
+			continue
+		}
+
+		if _, err := strconv.ParseInt(*bound, 10, 64); err != nil {
+			i.report(node, pointer, "bound %v is not an integer, ignored", *bound)
+			*bound = ""
+		}
+	}
+
+	if minimum != "" || maximum != "" {
+		if minimum == "" {
+			minimumOpen = "["
+		}
+		if maximum == "" {
+			maximumClose = "]"
+		}
+		result.expression += fmt.Sprintf("%v%v..%v%v", minimumOpen, minimum, maximum, maximumClose)
+	}
+
+	if multipleOf := lookupNode(node, "multipleOf"); multipleOf != nil {
+		if number, err := strconv.ParseInt(multipleOf.Value, 10, 64); err == nil && number > 0 {
+			result.multipleOf = multipleOf.Value
+		} else {
+			i.report(multipleOf, pointer, "multipleOf %v is not a positive integer, ignored", multipleOf.Value)
+		}
+	}
+
+	return result
+}
+
+// countBounds returns the "(min,max)" suffix of arrays and maps.
+func (i *jsonSchemaImporter) countBounds(node *yaml.Node, pointer string, minKeyword string, maxKeyword string) string {
+	minimum := lookupNode(node, minKeyword)
+	maximum := lookupNode(node, maxKeyword)
+
+	switch {
+	case minimum != nil && maximum != nil:
+		return fmt.Sprintf("(%v,%v)", minimum.Value, maximum.Value)
+	case maximum != nil:
+		return fmt.Sprintf("(0,%v)", maximum.Value)
+	case minimum != nil:
+		i.report(minimum, pointer, "%v without %v is not supported, ignored", minKeyword, maxKeyword)
+	}
+
+	return ""
+}
+
+// itemType converts the items of arrays and maps, which can't have lengths
+// of their own: the bounds of the expression count the items.
+func (i *jsonSchemaImporter) itemType(inlineName string, node *yaml.Node, pointer string) (importedType, bool) {
+	item, nullable := i.nonNullSchema(node, pointer)
+	if item == nil {
+		return importedType{}, false
+	}
+
+	if nullable {
+		i.report(node, pointer, "nullable items are not supported, imported as required")
+	}
+
+	if lookupNode(item, "$ref") == nil {
+		item = i.withoutLengths(item, pointer)
+	}
+
+	result, ok := i.convert(inlineName, item, pointer)
+	if !ok {
+		return importedType{}, false
+	}
+
+	if strings.HasPrefix(result.expression, "[]") || strings.HasPrefix(result.expression, "map[") {
+		i.report(node, pointer, "nested arrays and maps are not supported, dropped")
+		return importedType{}, false
+	}
+
+	return result, true
+}
+
+// withoutLengths returns a copy of node without its length keywords.
+func (i *jsonSchemaImporter) withoutLengths(node *yaml.Node, pointer string) *yaml.Node {
+	stripped := *node
+	stripped.Content = []*yaml.Node{}
+
+	for index := 0; index+1 < len(node.Content); index += 2 {
+		key := node.Content[index]
+		if key.Value == "minLength" || key.Value == "maxLength" {
+			i.report(key, pointer, "%v of items is not supported, ignored", key.Value)
+			continue
+		}
+
+		stripped.Content = append(stripped.Content, key, node.Content[index+1])
+	}
+
+	return &stripped
+}
+
+func (i *jsonSchemaImporter) arrayType(inlineName string, node *yaml.Node, pointer string) (importedType, bool) {
+	items := lookupNode(node, "items")
+	if items == nil || items.Kind != yaml.MappingNode {
+		i.report(node, pointer, "array without a schema of its items is not supported, dropped")
+		return importedType{}, false
+	}
+
+	result, ok := i.itemType(inlineName+"Item", items, pointer+"/items")
+	if !ok {
+		return importedType{}, false
+	}
+
+	result.expression = "[]" + result.expression + i.countBounds(node, pointer, "minItems", "maxItems")
+	return result, true
+}
+
+func (i *jsonSchemaImporter) mapType(inlineName string, node *yaml.Node, pointer string) (importedType, bool) {
+	additional := lookupNode(node, "additionalProperties")
+	if additional == nil || additional.Kind != yaml.MappingNode {
+		i.report(node, pointer, "object without properties or a schema of additionalProperties is not supported, dropped")
+		return importedType{}, false
+	}
+
+	result, ok := i.itemType(inlineName+"Value", additional, pointer+"/additionalProperties")
+	if !ok {
+		return importedType{}, false
+	}
+
+	keyType := "string"
+	if propertyNames := lookupNode(node, "propertyNames"); propertyNames != nil {
+		format := lookupNode(propertyNames, "format")
+		pattern := lookupNode(propertyNames, "pattern")
+
+		switch {
+		case format != nil && (format.Value == "uuid" || format.Value == "email"):
+			keyType = format.Value
+		case pattern != nil && pattern.Value == "^-?[0-9]+$":
+			keyType = "int64"
+		default:
+			i.report(propertyNames, pointer, "propertyNames other than uuid, email or integer keys are not supported, ignored")
+		}
+	}
+
+	result.expression = fmt.Sprintf("map[%v]%v%v", keyType, result.expression, i.countBounds(node, pointer, "minProperties", "maxProperties"))
+	return result, true
+}
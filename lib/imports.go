@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+type goImport struct {
+	Name string
+	Path string
+}
+
+// buildImports returns an import block with only those candidates whose
+// package is referenced in code, so generated files never carry unused
+// imports. Comments and string literals, e.g. descriptions mentioning
+// time.Duration, don't count as references.
+func buildImports(code string, candidates ...goImport) string {
+	usedPackages, isParsed := getUsedPackages(code)
+
+	specs := ""
+	for _, candidate := range candidates {
+		// code that can't be parsed is reported when formatted
+		if isParsed && !usedPackages[candidate.Name] {
+			continue
+		}
+
+		if strings.HasSuffix(candidate.Path, "/"+candidate.Name) || candidate.Path == candidate.Name {
+			specs += fmt.Sprintf("%q\n", candidate.Path)
+		} else {
+			specs += fmt.Sprintf("%v %q\n", candidate.Name, candidate.Path)
+		}
+	}
+
+	if specs == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("import (\n%v)\n", specs)
+}
+
+// getUsedPackages returns the names of packages referenced by selectors like
+// time.Duration in code, those whose name isn't declared in code itself.
+func getUsedPackages(code string) (map[string]bool, bool) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package generated\n"+code, 0)
+	if err != nil {
+		return nil, false
+	}
+
+	result := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+			result[ident.Name] = true
+		}
+
+		return true
+	})
+
+	return result, true
+}
+
+func packageGoImports(service *Service) []goImport {
+	result := []goImport{}
+	seen := map[string]bool{}
+
+	for _, schemaImport := range packageImports(service) {
+		if seen[schemaImport.Namespace] {
+			continue
+		}
+		seen[schemaImport.Namespace] = true
+
+		result = append(result, goImport{schemaImport.Namespace, schemaImport.GoPackage})
+	}
+
+	return result
+}
@@ -0,0 +1,228 @@
+package lib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func LoadService(serviceSchemaPath string) (*Service, error) {
+	loader := serviceLoader{
+		services: map[string]*Service{},
+	}
+
+	service, diagnostics := loader.load(serviceSchemaPath, nil)
+	if service != nil {
+		diagnostics = append(diagnostics, validateGeneratedPackage(service).inFile(serviceSchemaPath)...)
+	}
+
+	if len(diagnostics) > 0 {
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			if diagnostics[i].File != diagnostics[j].File {
+				if diagnostics[i].File == serviceSchemaPath || diagnostics[j].File == serviceSchemaPath {
+					return diagnostics[i].File == serviceSchemaPath
+				}
+				return diagnostics[i].File < diagnostics[j].File
+			}
+			if diagnostics[i].Line != diagnostics[j].Line {
+				return diagnostics[i].Line < diagnostics[j].Line
+			}
+			return diagnostics[i].Column < diagnostics[j].Column
+		})
+
+		return nil, diagnostics
+	}
+
+	return service, nil
+}
+
+type serviceLoader struct {
+	services map[string]*Service
+}
+
+// load parses the schema at path and, recursively, the schemas it imports.
+// stack holds the absolute paths of the schemas currently being loaded and
+// is used to detect import cycles.
+func (l *serviceLoader) load(path string, stack []string) (*Service, Diagnostics) {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, Diagnostics{{File: path, Message: err.Error()}}
+	}
+
+	if service, ok := l.services[absolutePath]; ok {
+		return service, nil
+	}
+
+	rawSchema, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, Diagnostics{{File: path, Message: err.Error()}}
+	}
+
+	service := Service{}
+	err = yaml.Unmarshal(rawSchema, &service)
+
+	diagnostics, isDiagnostics := err.(Diagnostics)
+	if err != nil && !isDiagnostics {
+		return nil, Diagnostics{syntaxErrorDiagnostic(err)}.inFile(path)
+	}
+
+	stack = append(stack, absolutePath)
+	for index := range service.Imports {
+		schemaImport := &service.Imports[index]
+
+		importPath := schemaImport.Path
+		if !filepath.IsAbs(importPath) {
+			importPath = filepath.Join(filepath.Dir(path), importPath)
+		}
+
+		if cycle := importCycle(stack, importPath); cycle != "" {
+			diagnostics.add(schemaImport.Position, "import cycle: %v", cycle)
+			continue
+		}
+
+		importedService, importDiagnostics := l.load(importPath, stack)
+		diagnostics = append(diagnostics, importDiagnostics...)
+		schemaImport.Service = importedService
+	}
+
+	diagnostics = append(diagnostics, validateService(&service)...)
+	l.services[absolutePath] = &service
+
+	return &service, diagnostics.inFile(path)
+}
+
+func importCycle(stack []string, importPath string) string {
+	absoluteImportPath, err := filepath.Abs(importPath)
+	if err != nil {
+		return ""
+	}
+
+	for index, path := range stack {
+		if path != absoluteImportPath {
+			continue
+		}
+
+		cycle := []string{}
+		for _, cyclePath := range stack[index:] {
+			cycle = append(cycle, filepath.Base(cyclePath))
+		}
+
+		return strings.Join(append(cycle, filepath.Base(absoluteImportPath)), " -> ")
+	}
+
+	return ""
+}
+
+// generatedTypes returns the types emitted into the package of service: its
+// own types followed by the types of imports without a GoPackage, including
+// the ones those imports bring in the same way.
+func generatedTypes(service *Service) TypesData {
+	result := TypesData{}
+	for _, generatedService := range generatedServices(service) {
+		result = append(result, generatedService.Types...)
+	}
+
+	return result
+}
+
+func generatedServices(service *Service) []*Service {
+	result := []*Service{}
+	visited := map[*Service]bool{}
+
+	var visit func(service *Service)
+	visit = func(service *Service) {
+		if service == nil || visited[service] {
+			return
+		}
+		visited[service] = true
+		result = append(result, service)
+
+		for _, schemaImport := range service.Imports {
+			if schemaImport.GoPackage == "" {
+				visit(schemaImport.Service)
+			}
+		}
+	}
+	visit(service)
+
+	return result
+}
+
+// packageImports returns the already generated Go packages referenced from
+// the package of service, keyed by the namespace they are imported under.
+func packageImports(service *Service) []Import {
+	result := []Import{}
+	for _, generatedService := range generatedServices(service) {
+		for _, schemaImport := range generatedService.Imports {
+			if schemaImport.GoPackage != "" {
+				result = append(result, schemaImport)
+			}
+		}
+	}
+
+	return result
+}
+
+// validateGeneratedPackage checks that everything generated into a single Go
+// package fits together: type names and import namespaces must not clash.
+func validateGeneratedPackage(service *Service) Diagnostics {
+	diagnostics := Diagnostics{}
+
+	importedVia := map[*Service]Import{}
+	for _, schemaImport := range service.Imports {
+		for _, importedService := range generatedServices(schemaImport.Service) {
+			if _, ok := importedVia[importedService]; !ok {
+				importedVia[importedService] = schemaImport
+			}
+		}
+	}
+
+	definedIn := map[string]*Service{}
+	for _, generatedService := range generatedServices(service) {
+		for _, definition := range generatedService.Types {
+			goName := strings.Title(string(definition.Name))
+
+			previous, ok := definedIn[goName]
+			if !ok {
+				definedIn[goName] = generatedService
+				continue
+			}
+
+			schemaImport := importedVia[generatedService]
+			if previous == service {
+				diagnostics.add(schemaImport.Position, "type %v of import %v conflicts with type %v of this schema", definition.Name, schemaImport.Namespace, goName)
+			} else {
+				diagnostics.add(schemaImport.Position, "type %v of import %v conflicts with type %v of import %v", definition.Name, schemaImport.Namespace, goName, importedVia[previous].Namespace)
+			}
+		}
+	}
+
+	goPackages := map[string]string{}
+	for _, generatedService := range generatedServices(service) {
+		for _, schemaImport := range generatedService.Imports {
+			if schemaImport.GoPackage == "" {
+				continue
+			}
+
+			position := schemaImport.Position
+			if generatedService != service {
+				position = importedVia[generatedService].Position
+			}
+
+			goPackage, ok := goPackages[schemaImport.Namespace]
+			if ok && goPackage != schemaImport.GoPackage {
+				diagnostics.add(position, "namespace %v refers to both %v and %v", schemaImport.Namespace, goPackage, schemaImport.GoPackage)
+			}
+			goPackages[schemaImport.Namespace] = schemaImport.GoPackage
+		}
+	}
+
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	return diagnostics
+}
@@ -1,70 +1,163 @@
 package lib
 
 import (
-	"gopkg.in/yaml.v2"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
 type TypeName string
 type FieldName string
-type StructTypeData map[FieldName]TypeInfo
+type StructTypeData []Field
+
+type Field struct {
+	Name     FieldName
+	TypeInfo TypeInfo
+}
+
+// Documentation describes a type, field, param, enum value or method. Deprecated
+// ones have IsDeprecated set and Deprecation may say what to use instead.
+type Documentation struct {
+	Description  string
+	IsDeprecated bool
+	Deprecation  string
+}
+
 type TypeInfo struct {
+	Documentation
 	IsCustomType bool
+	Namespace    string
+	Package      string
 	DataType     string
 	IsArray      bool
+	IsMap        bool
+	KeyType      string
 	IsOptional   bool
 	Min          int
 	Max          int
+	Minimum      *Bound
+	Maximum      *Bound
+	MultipleOf   int64
+	Pattern      string
 	IsVariable   bool
 	MapField     FieldName
-	Mapping      map[string]TypeInfo
+	Mapping      []MappingCase
+	Position     Position
+
+	// isInvalid marks the placeholder of a field whose type couldn't be
+	// decoded, checks of other fields referencing it are skipped
+	isInvalid bool
+}
+
+type Bound struct {
+	Value     int64
+	Exclusive bool
+}
+
+type MappingCase struct {
+	Value    string
+	TypeInfo TypeInfo
 }
 
 type EnumTypeData struct {
-	Type          string
-	ValuesString  map[string]string
-	ValuesInteger map[string]int
+	Type     string
+	Values   []EnumValue
+	Position Position
+}
+
+type EnumValue struct {
+	Documentation
+	Name         string
+	StringValue  string
+	IntegerValue int
+	Position     Position
 }
 
-func getTypeInfo(schemaType string) TypeInfo {
+var typeExpressionRegexp = regexp.MustCompile(`^(?P<array>\[\])?(map\[(?P<key>\w+)\])?((?P<namespace>\w+)\.)?(?P<type>[\w]+)((?P<rangeOpen>[\[(])(?P<minimum>-?[0-9]+)?\.\.(?P<maximum>-?[0-9]+)?(?P<rangeClose>[\])]))?(\((?P<min>[0-9]+)\s*(,\s*(?P<max>[0-9]+))?\))?(?P<optional>[?])?$`)
+
+func parseTypeInfo(schemaType string) (TypeInfo, error) {
 	result := TypeInfo{
 		Max: -1,
 		Min: 0,
 	}
 
-	var r = regexp.MustCompile(`^(?P<array>\[\])?(?P<type>[\w]+)(\((?P<min>[0-9]+)\s*(,\s*(?P<max>[0-9]+))?\))?(?P<optional>[?])?$`)
-	matches := r.FindAllStringSubmatch(schemaType, -1)
-	groups := r.SubexpNames()
+	matches := typeExpressionRegexp.FindStringSubmatch(strings.TrimSpace(schemaType))
+	if matches == nil {
+		return result, fmt.Errorf("malformed type expression %q", schemaType)
+	}
 
-	var value string
-	for index, group := range groups {
+	var err error
+	var rangeOpen, rangeClose string
+	for index, group := range typeExpressionRegexp.SubexpNames() {
 
-		value = matches[0][index]
+		value := matches[index]
 
 		switch group {
 		case "array":
 			result.IsArray = (value != "")
+		case "key":
+			result.IsMap = (value != "")
+			result.KeyType = value
 		case "optional":
 			result.IsOptional = (value != "")
+		case "namespace":
+			result.Namespace = value
 		case "type":
 			result.DataType = value
 		case "min":
 			if value != "" {
-				result.Min, _ = strconv.Atoi(value)
-				result.Max, _ = strconv.Atoi(value)
+				result.Min, err = strconv.Atoi(value)
+				result.Max = result.Min
 			}
 		case "max":
 			if value != "" {
-				result.Max, _ = strconv.Atoi(value)
+				result.Max, err = strconv.Atoi(value)
+			}
+		case "rangeOpen":
+			rangeOpen = value
+		case "rangeClose":
+			rangeClose = value
+		case "minimum":
+			if value != "" {
+				result.Minimum = &Bound{}
+				result.Minimum.Value, err = strconv.ParseInt(value, 10, 64)
+			}
+		case "maximum":
+			if value != "" {
+				result.Maximum = &Bound{}
+				result.Maximum.Value, err = strconv.ParseInt(value, 10, 64)
 			}
 		}
+
+		if err != nil {
+			return result, fmt.Errorf("malformed type expression %q: %v", schemaType, err)
+		}
+	}
+
+	if result.Minimum != nil {
+		result.Minimum.Exclusive = rangeOpen == "("
+	}
+
+	if result.Maximum != nil {
+		result.Maximum.Exclusive = rangeClose == ")"
+	}
+
+	if result.IsArray && result.IsMap {
+		return result, fmt.Errorf("malformed type expression %q: arrays of maps are not supported", schemaType)
 	}
 
 	result.IsCustomType = strings.Title(result.DataType) == result.DataType
 
-	return result
+	return result, nil
 }
 
 type MethodName string
@@ -76,149 +169,815 @@ type Parameter struct {
 }
 
 type MethodData struct {
-	Params []Parameter `json:"params"`
-	Result TypeInfo    `json:"result"`
+	Documentation
+	Params   []Parameter   `json:"params"`
+	Result   TypeInfo      `json:"result"`
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Errors   []MethodError `json:"errors,omitempty"`
+	Position Position
 }
 
-func (f *MethodData) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	parsedData := struct {
-		Params yaml.MapSlice `json:"params"`
-		Result TypeName      `json:"result"`
-	}{}
+type ErrorName string
 
-	err := unmarshal(&parsedData)
-	if err != nil {
-		return err
+// ErrorDefinition is an error methods may declare. Message may reference
+// fields of Data as {field}.
+type ErrorDefinition struct {
+	Name     ErrorName      `json:"name"`
+	Code     int            `json:"code"`
+	Message  string         `json:"message"`
+	Data     StructTypeData `json:"data"`
+	Position Position
+}
+
+type MethodError struct {
+	Name     ErrorName
+	Position Position
+}
+
+type TypeMapping struct {
+	MapField string              `json:"mapField"`
+	Mapping  map[string]TypeName `json:"mapping"`
+}
+
+type TypeDefinition struct {
+	Documentation
+	Name TypeName
+	Data interface{}
+}
+
+type TypesData []TypeDefinition
+
+func (t TypesData) Get(name TypeName) (interface{}, bool) {
+	for _, definition := range t {
+		if definition.Name == name {
+			return definition.Data, true
+		}
+	}
+
+	return nil, false
+}
+
+type Method struct {
+	Name MethodName
+	MethodData
+}
+
+// Import is a schema referenced from another one under a namespace. Types of
+// an import without GoPackage are generated into the importing package, those
+// with GoPackage are referenced from the already generated Go package.
+type Import struct {
+	Namespace string
+	Path      string
+	GoPackage string
+	Position  Position
+	Service   *Service
+}
+
+type Service struct {
+	Version     string            `json:"version"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Imports     []Import          `json:"imports"`
+	Types       TypesData         `json:"types"`
+	Errors      []ErrorDefinition `json:"errors"`
+	Methods     []Method          `json:"methods"`
+	Package     string            `json:"package"`
+	Position    Position
+}
+
+func (s *Service) importByNamespace(namespace string) (*Import, bool) {
+	for index := range s.Imports {
+		if s.Imports[index].Namespace == namespace {
+			return &s.Imports[index], true
+		}
 	}
 
-	*f = MethodData{
-		Params: []Parameter{},
+	return nil, false
+}
+
+func (s *Service) lookupType(typeInfo TypeInfo) (interface{}, bool) {
+	if typeInfo.Namespace == "" {
+		return s.Types.Get(TypeName(typeInfo.DataType))
 	}
-	f.Result = getTypeInfo(string(parsedData.Result))
 
-	for _, data := range parsedData.Params {
-		f.Params = append(f.Params, Parameter{
-			Name:     ParamName(data.Key.(string)),
-			TypeInfo: getTypeInfo(string(data.Value.(string))),
-		})
+	schemaImport, ok := s.importByNamespace(typeInfo.Namespace)
+	if !ok || schemaImport.Service == nil {
+		return nil, false
 	}
 
-	return err
+	return schemaImport.Service.Types.Get(TypeName(typeInfo.DataType))
 }
 
-type TypeMapping struct {
-	MapField string              `json:"mapField"`
-	Mapping  map[string]TypeName `json:"mapping"`
+// forEachTypeInfo calls visit for every type expression of the schema: struct
+// fields, variable field mappings, error data, method params and results.
+func (s *Service) forEachTypeInfo(visit func(typeInfo *TypeInfo, what string)) {
+	for _, definition := range s.Types {
+		structData, ok := definition.Data.(StructTypeData)
+		if !ok {
+			continue
+		}
+
+		for fieldIndex := range structData {
+			field := &structData[fieldIndex]
+			what := fmt.Sprintf("field %v.%v", definition.Name, field.Name)
+			visit(&field.TypeInfo, what)
+
+			for caseIndex := range field.TypeInfo.Mapping {
+				mappingCase := &field.TypeInfo.Mapping[caseIndex]
+				visit(&mappingCase.TypeInfo, fmt.Sprintf("mapping %q of %v", mappingCase.Value, what))
+			}
+		}
+	}
+
+	for errorIndex := range s.Errors {
+		definition := &s.Errors[errorIndex]
+		for fieldIndex := range definition.Data {
+			field := &definition.Data[fieldIndex]
+			visit(&field.TypeInfo, fmt.Sprintf("data field %v of error %v", field.Name, definition.Name))
+		}
+	}
+
+	for methodIndex := range s.Methods {
+		method := &s.Methods[methodIndex]
+		for paramIndex := range method.Params {
+			param := &method.Params[paramIndex]
+			visit(&param.TypeInfo, fmt.Sprintf("param %v of method %v", param.Name, method.Name))
+		}
+
+		visit(&method.Result, fmt.Sprintf("result of method %v", method.Name))
+	}
+}
+
+func (s *Service) UnmarshalYAML(node *yaml.Node) error {
+	d := schemaDecoder{}
+	*s = Service{
+		Types:    TypesData{},
+		Errors:   []ErrorDefinition{},
+		Methods:  []Method{},
+		Position: positionOf(node),
+	}
+
+	for _, pair := range d.mapping(node, "service schema") {
+		switch pair.name {
+		case "version":
+			s.Version, _ = d.scalar(pair.value, "version")
+		case "name":
+			s.Name, _ = d.scalar(pair.value, "name")
+		case "description":
+			s.Description, _ = d.scalar(pair.value, "description")
+		case "package":
+			s.Package, _ = d.scalar(pair.value, "package")
+		case "imports":
+			s.Imports = d.imports(pair.value)
+		case "types":
+			s.Types = d.types(pair.value)
+		case "errors":
+			s.Errors = d.errors(pair.value)
+		case "methods":
+			s.Methods = d.methods(pair.value)
+		default:
+			d.errorf(pair.key, "unknown schema key %q", pair.name)
+		}
+	}
+
+	if len(d.diagnostics) > 0 {
+		return d.diagnostics
+	}
+
+	return nil
+}
+
+// schemaDecoder walks the YAML node tree of a schema and collects every
+// structural problem instead of stopping at the first one.
+type schemaDecoder struct {
+	diagnostics Diagnostics
 }
 
-type TypesData map[TypeName]interface{}
+type nodePair struct {
+	name  string
+	key   *yaml.Node
+	value *yaml.Node
+}
+
+func positionOf(node *yaml.Node) Position {
+	return Position{Line: node.Line, Column: node.Column}
+}
+
+func (d *schemaDecoder) errorf(node *yaml.Node, format string, args ...interface{}) {
+	d.diagnostics.add(positionOf(node), format, args...)
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
 
-func (t *TypesData) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	parsedTypes := map[TypeName]interface{}{}
+	return node
+}
 
-	err := unmarshal(&parsedTypes)
+func (d *schemaDecoder) mapping(node *yaml.Node, what string) []nodePair {
+	node = resolveAlias(node)
+	if node.Kind != yaml.MappingNode {
+		d.errorf(node, "%v must be a mapping", what)
+		return nil
+	}
+
+	pairs := []nodePair{}
+	seen := map[string]*yaml.Node{}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := resolveAlias(node.Content[i])
+		value := resolveAlias(node.Content[i+1])
+
+		if key.Kind != yaml.ScalarNode {
+			d.errorf(key, "keys of %v must be strings", what)
+			continue
+		}
+
+		if previous, ok := seen[key.Value]; ok {
+			d.errorf(key, "duplicate key %q in %v (first defined at line %v)", key.Value, what, previous.Line)
+			continue
+		}
+		seen[key.Value] = key
+
+		pairs = append(pairs, nodePair{name: key.Value, key: key, value: value})
+	}
+
+	return pairs
+}
+
+func (d *schemaDecoder) scalar(node *yaml.Node, what string) (string, bool) {
+	node = resolveAlias(node)
+	if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!null" {
+		d.errorf(node, "%v must be a string", what)
+		return "", false
+	}
+
+	return node.Value, true
+}
+
+func (d *schemaDecoder) typeInfo(node *yaml.Node, what string) (TypeInfo, bool) {
+	node = resolveAlias(node)
+	if node.Kind == yaml.MappingNode {
+		return d.longFormTypeInfo(node, what)
+	}
+
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+		d.errorf(node, "%v must be a type expression string", what)
+		return TypeInfo{}, false
+	}
+
+	expression := node.Value
+
+	typeInfo, err := parseTypeInfo(expression)
 	if err != nil {
-		return err
+		d.errorf(node, "%v: %v", what, err)
+		return TypeInfo{}, false
 	}
 
-	*t = TypesData{}
+	typeInfo.Position = positionOf(node)
+	return typeInfo, true
+}
 
-	for typeName, value := range parsedTypes {
+// longFormTypeInfo decodes the mapping form of a type expression, which
+// carries constraints and documentation that don't fit into the short string
+// form:
+//
+//	code:
+//	  type: string(1,10)
+//	  pattern: "^[A-Z]+$"
+//	  description: code of the product
+//	  deprecated: use sku instead
+func (d *schemaDecoder) longFormTypeInfo(node *yaml.Node, what string) (TypeInfo, bool) {
+	var typeNode *yaml.Node
+	pattern := ""
+	var multipleOf int64
+	documentation := Documentation{}
+
+	for _, pair := range d.mapping(node, what) {
+		switch pair.name {
+		case "type":
+			typeNode = pair.value
+		case "pattern":
+			value, ok := d.scalar(pair.value, fmt.Sprintf("pattern of %v", what))
+			if !ok {
+				continue
+			}
 
-		cleanedTypeName := strings.Title(strings.TrimSuffix(string(typeName), "(enum)"))
+			if _, err := regexp.Compile(value); err != nil {
+				d.errorf(pair.value, "pattern of %v is invalid: %v", what, err)
+				continue
+			}
+			pattern = value
+		case "multipleOf":
+			value, ok := d.scalar(pair.value, fmt.Sprintf("multipleOf of %v", what))
+			if !ok {
+				continue
+			}
 
-		var err error
-		var resultData interface{}
-		parsedDataType := value.(map[interface{}]interface{})
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || number <= 0 {
+				d.errorf(pair.value, "multipleOf of %v must be a positive integer", what)
+				continue
+			}
+			multipleOf = number
+		case "description":
+			documentation.Description, _ = d.scalar(pair.value, fmt.Sprintf("description of %v", what))
+		case "deprecated":
+			d.deprecation(pair.value, what, &documentation)
+		default:
+			d.errorf(pair.key, "unknown key %q in %v", pair.name, what)
+		}
+	}
 
-		if strings.HasSuffix(string(typeName), "(enum)") {
-			var enumData *EnumTypeData
-			enumData, err = unmarshalEnum(parsedDataType)
-			resultData = *enumData
+	if typeNode == nil {
+		d.errorf(node, "%v has no type", what)
+		return TypeInfo{}, false
+	}
 
+	if resolveAlias(typeNode).Kind == yaml.MappingNode {
+		d.errorf(typeNode, "type of %v must be a type expression string", what)
+		return TypeInfo{}, false
+	}
+
+	typeInfo, ok := d.typeInfo(typeNode, what)
+	typeInfo.Pattern = pattern
+	typeInfo.MultipleOf = multipleOf
+	typeInfo.Documentation = documentation
+
+	return typeInfo, ok
+}
+
+// deprecation decodes "deprecated: true" or a note saying what to use instead
+// like "deprecated: use sku instead".
+func (d *schemaDecoder) deprecation(node *yaml.Node, what string, documentation *Documentation) {
+	node = resolveAlias(node)
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!bool" {
+		var isDeprecated bool
+		if err := node.Decode(&isDeprecated); err == nil {
+			documentation.IsDeprecated = isDeprecated
+		}
+		return
+	}
+
+	deprecation, ok := d.scalar(node, fmt.Sprintf("deprecated of %v", what))
+	if ok {
+		documentation.IsDeprecated = true
+		documentation.Deprecation = deprecation
+	}
+}
+
+func (d *schemaDecoder) imports(node *yaml.Node) []Import {
+	result := []Import{}
+
+	for _, pair := range d.mapping(node, "imports") {
+		if !isIdentifier(pair.name) {
+			d.errorf(pair.key, "invalid import namespace %q", pair.name)
+			continue
+		}
+
+		schemaImport := Import{
+			Namespace: pair.name,
+			Position:  positionOf(pair.key),
+		}
+
+		if resolveAlias(pair.value).Kind == yaml.ScalarNode {
+			schemaImport.Path, _ = d.scalar(pair.value, fmt.Sprintf("path of import %v", pair.name))
 		} else {
-			var structData *StructTypeData
-			structData, err = unmarshalStructData(parsedDataType)
-			resultData = *structData
+			for _, importPair := range d.mapping(pair.value, fmt.Sprintf("import %v", pair.name)) {
+				switch importPair.name {
+				case "path":
+					schemaImport.Path, _ = d.scalar(importPair.value, fmt.Sprintf("path of import %v", pair.name))
+				case "goPackage":
+					schemaImport.GoPackage, _ = d.scalar(importPair.value, fmt.Sprintf("goPackage of import %v", pair.name))
+				default:
+					d.errorf(importPair.key, "unknown key %q in import %v", importPair.name, pair.name)
+				}
+			}
 		}
 
-		if err != nil {
-			return err
+		if schemaImport.Path == "" {
+			d.errorf(pair.value, "import %v has no path", pair.name)
+			continue
 		}
 
-		(*t)[TypeName(cleanedTypeName)] = resultData
+		result = append(result, schemaImport)
 	}
 
-	return err
+	return result
 }
 
-func unmarshalEnum(value map[interface{}]interface{}) (*EnumTypeData, error) {
+func (d *schemaDecoder) types(node *yaml.Node) TypesData {
+	result := TypesData{}
+	definedAt := map[TypeName]*yaml.Node{}
 
-	enumType := value["type"].(string)
+	for _, pair := range d.mapping(node, "types") {
+		isEnum := strings.HasSuffix(pair.name, "(enum)")
+		name := TypeName(strings.Title(strings.TrimSuffix(pair.name, "(enum)")))
 
-	valuesInteger := map[string]int{}
-	valuesString := map[string]string{}
+		if !isIdentifier(string(name)) {
+			d.errorf(pair.key, "invalid type name %q", pair.name)
+			continue
+		}
 
-	switch enumType {
-	case "string":
-		for key, value := range value["values"].(map[interface{}]interface{}) {
-			key := key.(string)
-			valuesString[key] = value.(string)
+		if previous, ok := definedAt[name]; ok {
+			d.errorf(pair.key, "type %v is already defined at line %v", name, previous.Line)
+			continue
 		}
-	case "int":
-		for key, value := range value["values"].(map[interface{}]interface{}) {
-			key := key.(string)
-			valuesInteger[key] = value.(int)
+		definedAt[name] = pair.key
+
+		definition := TypeDefinition{Name: name}
+		if isEnum {
+			definition.Data, definition.Documentation = d.enum(name, pair.value)
+		} else {
+			definition.Data, definition.Documentation = d.structData(name, pair.value)
 		}
+
+		result = append(result, definition)
 	}
 
-	return &EnumTypeData{
-		Type:          enumType,
-		ValuesString:  valuesString,
-		ValuesInteger: valuesInteger,
-	}, nil
+	return result
 }
 
-func unmarshalStructData(parsedData map[interface{}]interface{}) (*StructTypeData, error) {
+func (d *schemaDecoder) enum(name TypeName, node *yaml.Node) (EnumTypeData, Documentation) {
+	result := EnumTypeData{
+		Values:   []EnumValue{},
+		Position: positionOf(node),
+	}
+
+	documentation := Documentation{}
+	var valuesNode *yaml.Node
+	for _, pair := range d.mapping(node, fmt.Sprintf("enum %v", name)) {
+		switch pair.name {
+		case "type":
+			enumType, ok := d.scalar(pair.value, fmt.Sprintf("type of enum %v", name))
+			if !ok {
+				return result, documentation
+			}
+
+			if enumType != "string" && enumType != "int" {
+				d.errorf(pair.value, "enum %v has type %q, expected \"string\" or \"int\"", name, enumType)
+				return result, documentation
+			}
+			result.Type = enumType
+		case "values":
+			valuesNode = pair.value
+		case "description":
+			documentation.Description, _ = d.scalar(pair.value, fmt.Sprintf("description of enum %v", name))
+		case "deprecated":
+			d.deprecation(pair.value, fmt.Sprintf("enum %v", name), &documentation)
+		default:
+			d.errorf(pair.key, "unknown key %q in enum %v", pair.name, name)
+		}
+	}
 
+	if result.Type == "" {
+		d.errorf(node, "enum %v has no type", name)
+		return result, documentation
+	}
+
+	if valuesNode == nil {
+		d.errorf(node, "enum %v has no values", name)
+		return result, documentation
+	}
+
+	usedBy := map[string]string{}
+	for _, pair := range d.mapping(valuesNode, fmt.Sprintf("values of enum %v", name)) {
+		if !isIdentifier(pair.name) {
+			d.errorf(pair.key, "invalid value name %q in enum %v", pair.name, name)
+			continue
+		}
+
+		value, documentation, ok := d.enumValue(pair.value, fmt.Sprintf("value %v of enum %v", pair.name, name))
+		if !ok {
+			continue
+		}
+
+		enumValue := EnumValue{Documentation: documentation, Name: pair.name, StringValue: value, Position: positionOf(pair.key)}
+		if result.Type == "int" {
+			intValue, err := strconv.Atoi(value)
+			if err != nil {
+				d.errorf(pair.value, "value %v of enum %v must be an integer", pair.name, name)
+				continue
+			}
+			enumValue.IntegerValue = intValue
+		}
+		result.Values = append(result.Values, enumValue)
+
+		if previous, ok := usedBy[value]; ok {
+			d.errorf(pair.value, "enum %v has duplicate value %q (already used by %v)", name, value, previous)
+			continue
+		}
+		usedBy[value] = pair.name
+	}
+
+	return result, documentation
+}
+
+// enumValue decodes a value of an enum, either a plain scalar or the long form
+// with documentation:
+//
+//	magazine:
+//	  value: magazineItem
+//	  deprecated: true
+func (d *schemaDecoder) enumValue(node *yaml.Node, what string) (string, Documentation, bool) {
+	documentation := Documentation{}
+
+	node = resolveAlias(node)
+	if node.Kind != yaml.MappingNode {
+		value, ok := d.scalar(node, what)
+		return value, documentation, ok
+	}
+
+	var valueNode *yaml.Node
+	for _, pair := range d.mapping(node, what) {
+		switch pair.name {
+		case "value":
+			valueNode = pair.value
+		case "description":
+			documentation.Description, _ = d.scalar(pair.value, fmt.Sprintf("description of %v", what))
+		case "deprecated":
+			d.deprecation(pair.value, what, &documentation)
+		default:
+			d.errorf(pair.key, "unknown key %q in %v", pair.name, what)
+		}
+	}
+
+	if valueNode == nil {
+		d.errorf(node, "%v has no value", what)
+		return "", documentation, false
+	}
+
+	value, ok := d.scalar(valueNode, what)
+	return value, documentation, ok
+}
+
+// structDescriptionKey and structDeprecatedKey hold the documentation of a
+// struct type, they can't clash with field names:
+//
+//	Book:
+//	  (description): a book of the store
+//	  (deprecated): use Item instead
+//	  id: uuid
+const (
+	structDescriptionKey = "(description)"
+	structDeprecatedKey  = "(deprecated)"
+)
+
+func (d *schemaDecoder) structData(name TypeName, node *yaml.Node) (StructTypeData, Documentation) {
 	result := StructTypeData{}
-	for fieldName, value := range parsedData {
+	documentation := Documentation{}
+
+	for _, pair := range d.mapping(node, fmt.Sprintf("type %v", name)) {
+		switch pair.name {
+		case structDescriptionKey:
+			documentation.Description, _ = d.scalar(pair.value, fmt.Sprintf("description of type %v", name))
+			continue
+		case structDeprecatedKey:
+			d.deprecation(pair.value, fmt.Sprintf("type %v", name), &documentation)
+			continue
+		}
+
+		if strings.HasSuffix(pair.name, "?") {
+			fieldName := strings.TrimSuffix(pair.name, "?")
+			if !isIdentifier(fieldName) {
+				d.errorf(pair.key, "invalid field name %q in type %v", pair.name, name)
+				continue
+			}
+
+			if typeInfo, ok := d.variableField(name, FieldName(fieldName), pair.value); ok {
+				result = append(result, Field{Name: FieldName(fieldName), TypeInfo: typeInfo})
+			}
+			continue
+		}
+
+		if !isIdentifier(pair.name) {
+			d.errorf(pair.key, "invalid field name %q in type %v", pair.name, name)
+			continue
+		}
+
+		result = append(result, Field{Name: FieldName(pair.name), TypeInfo: d.fieldTypeInfo(pair.value, fmt.Sprintf("field %v.%v", name, pair.name))})
+	}
 
-		fieldName := fieldName.(string)
-		if strings.HasSuffix(fieldName, "?") {
+	return result, documentation
+}
 
-			fieldName := strings.TrimSuffix(fieldName, "?")
+// fieldTypeInfo returns an invalid placeholder for a type that can't be
+// decoded, so the field isn't reported as missing a second time.
+func (d *schemaDecoder) fieldTypeInfo(node *yaml.Node, what string) TypeInfo {
+	typeInfo, ok := d.typeInfo(node, what)
+	if !ok {
+		return TypeInfo{Position: positionOf(node), isInvalid: true}
+	}
 
-			value := value.(map[interface{}]interface{})
-			mapField := value["mapField"].(string)
+	return typeInfo
+}
 
-			mapping := map[string]TypeInfo{}
+func (d *schemaDecoder) variableField(name TypeName, fieldName FieldName, node *yaml.Node) (TypeInfo, bool) {
+	result := TypeInfo{
+		Mapping:    []MappingCase{},
+		IsVariable: true,
+		Position:   positionOf(node),
+	}
 
-			for key, value := range value["mapping"].(map[interface{}]interface{}) {
-				mapping[key.(string)] = getTypeInfo(value.(string))
+	what := fmt.Sprintf("variable field %v.%v", name, fieldName)
+	hasMapping := false
+
+	for _, pair := range d.mapping(node, what) {
+		switch pair.name {
+		case "mapField":
+			mapField, _ := d.scalar(pair.value, fmt.Sprintf("mapField of %v", what))
+			result.MapField = FieldName(mapField)
+		case "mapping":
+			hasMapping = true
+			for _, mappingPair := range d.mapping(pair.value, fmt.Sprintf("mapping of %v", what)) {
+				typeInfo, ok := d.typeInfo(mappingPair.value, fmt.Sprintf("mapping %q of %v", mappingPair.name, what))
+				if ok {
+					result.Mapping = append(result.Mapping, MappingCase{Value: mappingPair.name, TypeInfo: typeInfo})
+				}
 			}
+		case "description":
+			result.Description, _ = d.scalar(pair.value, fmt.Sprintf("description of %v", what))
+		case "deprecated":
+			d.deprecation(pair.value, what, &result.Documentation)
+		default:
+			d.errorf(pair.key, "unknown key %q in %v", pair.name, what)
+		}
+	}
+
+	if result.MapField == "" {
+		d.errorf(node, "%v has no mapField", what)
+		return result, false
+	}
+
+	if !hasMapping {
+		d.errorf(node, "%v has no mapping", what)
+		return result, false
+	}
 
-			result[FieldName(fieldName)] = TypeInfo{
-				MapField:   FieldName(mapField),
-				Mapping:    mapping,
-				IsVariable: true,
+	return result, true
+}
+
+func (d *schemaDecoder) errors(node *yaml.Node) []ErrorDefinition {
+	result := []ErrorDefinition{}
+
+	for _, pair := range d.mapping(node, "errors") {
+		if !isIdentifier(pair.name) {
+			d.errorf(pair.key, "invalid error name %q", pair.name)
+			continue
+		}
+
+		definition := ErrorDefinition{
+			Name:     ErrorName(pair.name),
+			Data:     StructTypeData{},
+			Position: positionOf(pair.key),
+		}
+
+		hasCode := false
+		for _, errorPair := range d.mapping(pair.value, fmt.Sprintf("error %v", pair.name)) {
+			switch errorPair.name {
+			case "code":
+				hasCode = true
+
+				value, ok := d.scalar(errorPair.value, fmt.Sprintf("code of error %v", pair.name))
+				if !ok {
+					continue
+				}
+
+				code, err := strconv.Atoi(value)
+				if err != nil {
+					d.errorf(errorPair.value, "code of error %v must be an integer, got %q", pair.name, value)
+					continue
+				}
+				definition.Code = code
+			case "message":
+				definition.Message, _ = d.scalar(errorPair.value, fmt.Sprintf("message of error %v", pair.name))
+			case "data":
+				for _, fieldPair := range d.mapping(errorPair.value, fmt.Sprintf("data of error %v", pair.name)) {
+					if !isIdentifier(fieldPair.name) {
+						d.errorf(fieldPair.key, "invalid data field name %q in error %v", fieldPair.name, pair.name)
+						continue
+					}
+
+					definition.Data = append(definition.Data, Field{
+						Name:     FieldName(fieldPair.name),
+						TypeInfo: d.fieldTypeInfo(fieldPair.value, fmt.Sprintf("data field %v of error %v", fieldPair.name, pair.name)),
+					})
+				}
+			default:
+				d.errorf(errorPair.key, "unknown key %q in error %v", errorPair.name, pair.name)
 			}
+		}
 
-		} else {
+		if !hasCode {
+			d.errorf(pair.key, "error %v has no code", pair.name)
+		}
+
+		result = append(result, definition)
+	}
+
+	return result
+}
+
+func (d *schemaDecoder) methodErrors(name MethodName, node *yaml.Node) []MethodError {
+	result := []MethodError{}
 
-			value := value.(string)
-			result[FieldName(fieldName)] = getTypeInfo(value)
+	node = resolveAlias(node)
+	if node.Kind != yaml.SequenceNode {
+		d.errorf(node, "errors of method %v must be a list of error names", name)
+		return result
+	}
+
+	for _, item := range node.Content {
+		value, ok := d.scalar(item, fmt.Sprintf("error of method %v", name))
+		if ok {
+			result = append(result, MethodError{Name: ErrorName(value), Position: positionOf(item)})
 		}
 	}
 
-	return &result, nil
+	return result
 }
 
-type Service struct {
-	Version     string                    `json:"version"`
-	Name        string                    `json:"name"`
-	Description string                    `json:"description"`
-	Types       TypesData                 `json:"types"`
-	Methods     map[MethodName]MethodData `json:"methods"`
-	Package     string                    `json:"package"`
+func (d *schemaDecoder) methods(node *yaml.Node) []Method {
+	result := []Method{}
+
+	for _, pair := range d.mapping(node, "methods") {
+		if !isIdentifier(pair.name) {
+			d.errorf(pair.key, "invalid method name %q", pair.name)
+			continue
+		}
+
+		if methodData, ok := d.method(MethodName(pair.name), pair.value); ok {
+			result = append(result, Method{Name: MethodName(pair.name), MethodData: methodData})
+		}
+	}
+
+	return result
+}
+
+func (d *schemaDecoder) method(name MethodName, node *yaml.Node) (MethodData, bool) {
+	result := MethodData{
+		Params:   []Parameter{},
+		Position: positionOf(node),
+	}
+
+	var resultNode *yaml.Node
+	for _, pair := range d.mapping(node, fmt.Sprintf("method %v", name)) {
+		switch pair.name {
+		case "params":
+			if resolveAlias(pair.value).ShortTag() == "!!null" {
+				continue
+			}
+
+			for _, paramPair := range d.mapping(pair.value, fmt.Sprintf("params of method %v", name)) {
+				if !isIdentifier(paramPair.name) {
+					d.errorf(paramPair.key, "invalid param name %q in method %v", paramPair.name, name)
+					continue
+				}
+
+				result.Params = append(result.Params, Parameter{
+					Name:     ParamName(paramPair.name),
+					TypeInfo: d.fieldTypeInfo(paramPair.value, fmt.Sprintf("param %v of method %v", paramPair.name, name)),
+				})
+			}
+		case "result":
+			resultNode = pair.value
+		case "timeout":
+			value, ok := d.scalar(pair.value, fmt.Sprintf("timeout of method %v", name))
+			if !ok {
+				continue
+			}
+
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				d.errorf(pair.value, "timeout of method %v must be a positive duration like 500ms or 5s, got %q", name, value)
+				continue
+			}
+			result.Timeout = timeout
+		case "errors":
+			result.Errors = d.methodErrors(name, pair.value)
+		case "description":
+			result.Description, _ = d.scalar(pair.value, fmt.Sprintf("description of method %v", name))
+		case "deprecated":
+			d.deprecation(pair.value, fmt.Sprintf("method %v", name), &result.Documentation)
+		default:
+			d.errorf(pair.key, "unknown key %q in method %v", pair.name, name)
+		}
+	}
+
+	if resultNode == nil {
+		d.errorf(node, "method %v has no result", name)
+		return result, false
+	}
+
+	var ok bool
+	result.Result, ok = d.typeInfo(resultNode, fmt.Sprintf("result of method %v", name))
+
+	return result, ok
+}
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func isIdentifier(name string) bool {
+	return identifierRegexp.MatchString(name)
 }
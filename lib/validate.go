@@ -0,0 +1,257 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func validateService(service *Service) Diagnostics {
+	v := serviceValidator{service: service}
+
+	if service.Package == "" {
+		v.diagnostics.add(service.Position, "package is required")
+	} else if !isIdentifier(service.Package) {
+		v.diagnostics.add(service.Position, "invalid package name %q", service.Package)
+	}
+
+	service.forEachTypeInfo(v.checkTypeInfo)
+
+	for _, definition := range service.Types {
+		structData, ok := definition.Data.(StructTypeData)
+		if !ok {
+			continue
+		}
+
+		for _, field := range structData {
+			if field.TypeInfo.IsVariable {
+				v.checkVariableField(definition.Name, field, structData)
+			}
+		}
+	}
+
+	v.checkErrors()
+	v.checkEnumValues()
+
+	return v.diagnostics
+}
+
+type serviceValidator struct {
+	service     *Service
+	diagnostics Diagnostics
+}
+
+func (v *serviceValidator) checkTypeInfo(typeInfo *TypeInfo, what string) {
+	if typeInfo.IsVariable || typeInfo.isInvalid {
+		return
+	}
+
+	if typeInfo.IsMap {
+		if _, ok := mapKeyGoTypes[typeInfo.KeyType]; !ok {
+			v.diagnostics.add(typeInfo.Position, "%v has unsupported map key type %q", what, typeInfo.KeyType)
+		}
+	}
+
+	if typeInfo.Max >= 0 && typeInfo.Min > typeInfo.Max {
+		v.diagnostics.add(typeInfo.Position, "%v has min length %v greater than max length %v", what, typeInfo.Min, typeInfo.Max)
+	}
+
+	v.checkConstraints(typeInfo, what)
+
+	if typeInfo.Namespace != "" {
+		v.checkImportedType(typeInfo, what)
+		return
+	}
+
+	if _, isBuiltin := builtinGoTypes[typeInfo.DataType]; isBuiltin {
+		return
+	}
+
+	if !typeInfo.IsCustomType {
+		v.diagnostics.add(typeInfo.Position, "%v has unknown type %q", what, typeInfo.DataType)
+		return
+	}
+
+	if _, ok := v.service.Types.Get(TypeName(typeInfo.DataType)); !ok {
+		v.diagnostics.add(typeInfo.Position, "%v references undefined type %v", what, typeInfo.DataType)
+	}
+}
+
+func (v *serviceValidator) checkConstraints(typeInfo *TypeInfo, what string) {
+	goType := builtinGoTypes[typeInfo.DataType]
+	if typeInfo.Namespace != "" {
+		goType = ""
+	}
+
+	isNumber := goType == "int" || goType == "int64"
+	if (typeInfo.Minimum != nil || typeInfo.Maximum != nil) && !isNumber {
+		v.diagnostics.add(typeInfo.Position, "%v has a range but %v is not a number type", what, typeInfo.DataType)
+	}
+
+	if typeInfo.MultipleOf != 0 && !isNumber {
+		v.diagnostics.add(typeInfo.Position, "%v has multipleOf but %v is not a number type", what, typeInfo.DataType)
+	}
+
+	if typeInfo.Pattern != "" && goType != "string" {
+		v.diagnostics.add(typeInfo.Position, "%v has a pattern but %v is not a string type", what, typeInfo.DataType)
+	}
+
+	if typeInfo.Minimum == nil || typeInfo.Maximum == nil {
+		return
+	}
+
+	minimum, maximum := typeInfo.Minimum, typeInfo.Maximum
+	if minimum.Value > maximum.Value || (minimum.Value == maximum.Value && (minimum.Exclusive || maximum.Exclusive)) {
+		v.diagnostics.add(typeInfo.Position, "%v has an empty range", what)
+	}
+}
+
+func (v *serviceValidator) checkImportedType(typeInfo *TypeInfo, what string) {
+	schemaImport, ok := v.service.importByNamespace(typeInfo.Namespace)
+	if !ok {
+		v.diagnostics.add(typeInfo.Position, "%v references unknown import %v", what, typeInfo.Namespace)
+		return
+	}
+
+	typeInfo.Package = schemaImport.GoPackage
+
+	// an import that failed to load has already been reported
+	if schemaImport.Service == nil {
+		return
+	}
+
+	if !typeInfo.IsCustomType {
+		v.diagnostics.add(typeInfo.Position, "%v has unknown type %v.%v", what, typeInfo.Namespace, typeInfo.DataType)
+		return
+	}
+
+	if _, ok := schemaImport.Service.Types.Get(TypeName(typeInfo.DataType)); !ok {
+		v.diagnostics.add(typeInfo.Position, "%v references undefined type %v.%v", what, typeInfo.Namespace, typeInfo.DataType)
+	}
+}
+
+func (v *serviceValidator) checkVariableField(typeName TypeName, field Field, fields StructTypeData) {
+	what := fmt.Sprintf("variable field %v.%v", typeName, field.Name)
+
+	var mapFieldTypeInfo *TypeInfo
+	for _, candidate := range fields {
+		if candidate.Name == field.TypeInfo.MapField {
+			mapFieldTypeInfo = &candidate.TypeInfo
+			break
+		}
+	}
+
+	if mapFieldTypeInfo == nil {
+		v.diagnostics.add(field.TypeInfo.Position, "mapField of %v refers to unknown field %q", what, field.TypeInfo.MapField)
+		return
+	}
+
+	// the type of the field has already been reported
+	if mapFieldTypeInfo.isInvalid {
+		return
+	}
+
+	typeData, _ := v.service.lookupType(*mapFieldTypeInfo)
+	enumData, isEnum := typeData.(EnumTypeData)
+	if !isEnum || mapFieldTypeInfo.IsArray || mapFieldTypeInfo.IsMap || mapFieldTypeInfo.IsVariable {
+		v.diagnostics.add(field.TypeInfo.Position, "mapField of %v must refer to an enum field, %v is %v", what, field.TypeInfo.MapField, getGoType(*mapFieldTypeInfo))
+		return
+	}
+
+	for _, mappingCase := range field.TypeInfo.Mapping {
+		isKnownValue := false
+		for _, value := range enumData.Values {
+			if value.StringValue == mappingCase.Value {
+				isKnownValue = true
+				break
+			}
+		}
+
+		if !isKnownValue {
+			v.diagnostics.add(mappingCase.TypeInfo.Position, "mapping key %q of %v is not a value of enum %v", mappingCase.Value, what, mapFieldTypeInfo.DataType)
+		}
+	}
+}
+
+var messagePlaceholderRegexp = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func (v *serviceValidator) checkErrors() {
+	byCode := map[int]ErrorName{}
+	byName := map[ErrorName]bool{}
+
+	for _, definition := range v.service.Errors {
+		byName[definition.Name] = true
+
+		if definition.Code >= -32768 && definition.Code <= -32000 {
+			v.diagnostics.add(definition.Position, "code %v of error %v is reserved by JSON-RPC", definition.Code, definition.Name)
+		} else if previous, ok := byCode[definition.Code]; ok {
+			v.diagnostics.add(definition.Position, "error %v has the same code %v as error %v", definition.Name, definition.Code, previous)
+		} else {
+			byCode[definition.Code] = definition.Name
+		}
+
+		goName := getErrorTypeName(definition.Name)
+		if _, ok := v.service.Types.Get(TypeName(goName)); ok {
+			v.diagnostics.add(definition.Position, "error %v conflicts with type %v", definition.Name, goName)
+		}
+
+		for _, match := range messagePlaceholderRegexp.FindAllStringSubmatch(definition.Message, -1) {
+			field, ok := findField(definition.Data, FieldName(match[1]))
+			if !ok {
+				v.diagnostics.add(definition.Position, "message of error %v references unknown data field %v", definition.Name, match[1])
+				continue
+			}
+
+			if field.TypeInfo.isInvalid {
+				continue
+			}
+
+			typeInfo := field.TypeInfo
+			if typeInfo.IsCustomType || typeInfo.IsArray || typeInfo.IsMap || typeInfo.IsOptional {
+				v.diagnostics.add(definition.Position, "message of error %v can only reference required data fields of simple types, %v is %v", definition.Name, match[1], describeType(typeInfo))
+			}
+		}
+	}
+
+	for _, method := range v.service.Methods {
+		declared := map[ErrorName]bool{}
+		for _, methodError := range method.Errors {
+			if !byName[methodError.Name] {
+				v.diagnostics.add(methodError.Position, "method %v declares unknown error %v", method.Name, methodError.Name)
+			} else if declared[methodError.Name] {
+				v.diagnostics.add(methodError.Position, "method %v declares error %v twice", method.Name, methodError.Name)
+			}
+			declared[methodError.Name] = true
+		}
+	}
+}
+
+// checkEnumValues reports enum values whose Go constant has the name of a type,
+// an error or a value of another enum, types.go wouldn't compile.
+func (v *serviceValidator) checkEnumValues() {
+	goNames := map[string]string{}
+	for _, definition := range v.service.Types {
+		goNames[strings.Title(string(definition.Name))] = fmt.Sprintf("type %v", definition.Name)
+	}
+
+	for _, definition := range v.service.Errors {
+		goNames[getErrorTypeName(definition.Name)] = fmt.Sprintf("error %v", definition.Name)
+	}
+
+	for _, definition := range v.service.Types {
+		enumData, ok := definition.Data.(EnumTypeData)
+		if !ok {
+			continue
+		}
+
+		for _, value := range enumData.Values {
+			goName := strings.Title(value.Name)
+			if previous, ok := goNames[goName]; ok {
+				v.diagnostics.add(value.Position, "value %v of enum %v conflicts with %v, both are named %v in Go", value.Name, definition.Name, previous, goName)
+				continue
+			}
+
+			goNames[goName] = fmt.Sprintf("value %v of enum %v", value.Name, definition.Name)
+		}
+	}
+}
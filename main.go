@@ -2,6 +2,8 @@ package main
 
 import (
 	"bitbucket.org/timeio/go-service/lib"
+	"encoding/json"
+	"fmt"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 	"log"
@@ -17,6 +19,55 @@ func main() {
 			Name:   "build",
 			Usage:  "build migrations",
 			Action: build,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "context",
+					Usage: "pass context.Context to the executor and handler methods",
+				},
+				cli.StringFlag{
+					Name:  "target",
+					Value: "go",
+					Usage: "generated output: go, typescript, python, openrpc, jsonschema, protobuf or docs",
+				},
+			},
+		},
+		{
+			Name:      "validate",
+			Usage:     "validate service schemas without generating code",
+			ArgsUsage: "schema-file...",
+			Action:    validate,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Value: "human",
+					Usage: "report format: human or json",
+				},
+			},
+		},
+		{
+			Name:      "diff",
+			Usage:     "classify changes between two schema versions as compatible or breaking",
+			ArgsUsage: "old-schema-file new-schema-file",
+			Action:    diff,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Value: "human",
+					Usage: "report format: human or json",
+				},
+			},
+		},
+		{
+			Name:  "import",
+			Usage: "convert other schema formats to service schemas",
+			Subcommands: []cli.Command{
+				{
+					Name:      "jsonschema",
+					Usage:     "print the types section converted from a JSON Schema document",
+					ArgsUsage: "file",
+					Action:    importJsonSchema,
+				},
+			},
 		},
 	}
 
@@ -39,5 +90,173 @@ func build(c *cli.Context) error {
 		return errors.New("output path is required")
 	}
 
-	return lib.Build(filePath, outputPath)
+	return lib.Build(filePath, outputPath, lib.BuildOptions{
+		Context: c.Bool("context"),
+		Target:  c.String("target"),
+	})
+}
+
+type validationReport struct {
+	File        string          `json:"file"`
+	Valid       bool            `json:"valid"`
+	Diagnostics lib.Diagnostics `json:"diagnostics"`
+}
+
+func validate(c *cli.Context) error {
+	filePaths := c.Args()
+	if len(filePaths) == 0 {
+		return errors.New("file path is required")
+	}
+
+	format := c.String("format")
+	if format != "human" && format != "json" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	reports := []validationReport{}
+	problemsCount := 0
+
+	for _, filePath := range filePaths {
+		diagnostics := lib.ValidateSchema(filePath)
+		if diagnostics == nil {
+			diagnostics = lib.Diagnostics{}
+		}
+
+		problemsCount += len(diagnostics)
+		reports = append(reports, validationReport{
+			File:        filePath,
+			Valid:       len(diagnostics) == 0,
+			Diagnostics: diagnostics,
+		})
+	}
+
+	if format == "json" {
+		packed, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(packed))
+
+		if problemsCount > 0 {
+			return cli.NewExitError("", 1)
+		}
+		return nil
+	}
+
+	for _, report := range reports {
+		if report.Valid {
+			fmt.Printf("%v: ok\n", report.File)
+			continue
+		}
+
+		for _, diagnostic := range report.Diagnostics {
+			fmt.Println(diagnostic.String())
+		}
+	}
+
+	if problemsCount > 0 {
+		return cli.NewExitError(fmt.Sprintf("%v problem(s) found", problemsCount), 1)
+	}
+
+	return nil
+}
+
+type diffReport struct {
+	OldVersion   string       `json:"oldVersion"`
+	NewVersion   string       `json:"newVersion"`
+	Breaking     bool         `json:"breaking"`
+	VersionError string       `json:"versionError,omitempty"`
+	Changes      []lib.Change `json:"changes"`
+}
+
+func diff(c *cli.Context) error {
+	args := c.Args()
+
+	oldFilePath := args.Get(0)
+	newFilePath := args.Get(1)
+	if oldFilePath == "" || newFilePath == "" {
+		return errors.New("old and new schema paths are required")
+	}
+
+	format := c.String("format")
+	if format != "human" && format != "json" {
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	oldService, err := lib.LoadService(oldFilePath)
+	if err != nil {
+		return err
+	}
+
+	newService, err := lib.LoadService(newFilePath)
+	if err != nil {
+		return err
+	}
+
+	changes := lib.DiffServices(oldService, newService)
+	versionErr := lib.CheckVersionBump(oldService, newService, changes)
+
+	if format == "json" {
+		report := diffReport{
+			OldVersion: oldService.Version,
+			NewVersion: newService.Version,
+			Breaking:   lib.HasBreakingChanges(changes),
+			Changes:    changes,
+		}
+
+		if report.Changes == nil {
+			report.Changes = []lib.Change{}
+		}
+
+		if versionErr != nil {
+			report.VersionError = versionErr.Error()
+		}
+
+		packed, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(packed))
+
+		if versionErr != nil {
+			return cli.NewExitError("", 1)
+		}
+		return nil
+	}
+
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+
+	if versionErr != nil {
+		return cli.NewExitError(versionErr.Error(), 1)
+	}
+
+	return nil
+}
+
+func importJsonSchema(c *cli.Context) error {
+	filePath := c.Args().Get(0)
+	if filePath == "" {
+		return errors.New("file path is required")
+	}
+
+	types, diagnostics, err := lib.ImportJsonSchema(filePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(types))
+
+	if len(diagnostics) > 0 {
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintln(os.Stderr, diagnostic.String())
+		}
+
+		return cli.NewExitError(fmt.Sprintf("%v construct(s) can't be represented", len(diagnostics)), 1)
+	}
+
+	return nil
 }
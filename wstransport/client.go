@@ -0,0 +1,281 @@
+package wstransport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/akaumov/go-service/exchange"
+	"github.com/gorilla/websocket"
+)
+
+var ErrClientClosed = errors.New("client is closed")
+
+// Client calls methods of a Server over a single connection with JSON-RPC 2.0.
+// Calls and batches may be made concurrently. Client is an exchange.Transport for generated
+// clients.
+type Client struct {
+	socket *websocket.Conn
+
+	writeMutex sync.Mutex
+
+	mutex   sync.Mutex
+	pending map[string]chan []byte
+	nextId  uint64
+	err     error
+	closed  chan struct{}
+}
+
+func Dial(ctx context.Context, url string, header http.Header) (*Client, error) {
+	socket, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		socket:  socket,
+		pending: map[string]chan []byte{},
+		closed:  make(chan struct{}),
+	}
+
+	go c.read()
+
+	return c, nil
+}
+
+// Call calls method with params and unpacks its result into result, unless it
+// is nil. Errors of the server are returned as *exchange.Error.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	// RoundTrip replaces the id with one of the connection
+	return exchange.Call(ctx, c, 0, method, params, result)
+}
+
+// RoundTrip sends a packed request or batch and waits for its response.
+// Notifications are only sent. Ids of requests are replaced with ids of the
+// connection while sent, so ids of concurrent callers can't collide, and are
+// restored in the response.
+func (c *Client) RoundTrip(ctx context.Context, request []byte) ([]byte, error) {
+	requests, isBatch, err := unpackMessages(request)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse request: %v", err)
+	}
+
+	if len(requests) == 0 {
+		return nil, errors.New("batch must not be empty")
+	}
+
+	c.mutex.Lock()
+	if c.err != nil {
+		c.mutex.Unlock()
+		return nil, c.err
+	}
+
+	// ids of the connection to ids of the request
+	ids := map[string]json.RawMessage{}
+	responses := make(chan []byte, 1)
+
+	for _, request := range requests {
+		requestId, ok := request["id"]
+		if !ok {
+			continue
+		}
+
+		c.nextId++
+		id := strconv.FormatUint(c.nextId, 10)
+
+		request["id"] = json.RawMessage(id)
+		ids[id] = requestId
+		c.pending[id] = responses
+	}
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		for id := range ids {
+			delete(c.pending, id)
+		}
+		c.mutex.Unlock()
+	}()
+
+	packed, err := packMessages(requests, isBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.write(packed)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	select {
+	case response := <-responses:
+		return restoreIds(response, ids)
+
+	case <-c.closed:
+		return nil, c.closeErr()
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// restoreIds replaces ids of the connection in a response with ids of the request.
+func restoreIds(response []byte, ids map[string]json.RawMessage) ([]byte, error) {
+	messages, isBatch, err := unpackMessages(response)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse response: %v", err)
+	}
+
+	for _, message := range messages {
+		if requestId, ok := ids[string(message["id"])]; ok {
+			message["id"] = requestId
+		}
+	}
+
+	return packMessages(messages, isBatch)
+}
+
+// unpackMessages unpacks a JSON-RPC message or batch into its objects.
+func unpackMessages(packed []byte) ([]map[string]json.RawMessage, bool, error) {
+	var messages []map[string]json.RawMessage
+
+	packed = bytes.TrimSpace(packed)
+	isBatch := len(packed) > 0 && packed[0] == '['
+
+	var err error
+	if isBatch {
+		err = json.Unmarshal(packed, &messages)
+	} else {
+		messages = make([]map[string]json.RawMessage, 1)
+		err = json.Unmarshal(packed, &messages[0])
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, message := range messages {
+		if message == nil {
+			return nil, false, errors.New("message must be an object")
+		}
+	}
+
+	return messages, isBatch, nil
+}
+
+func packMessages(messages []map[string]json.RawMessage, isBatch bool) ([]byte, error) {
+	if isBatch {
+		return json.Marshal(messages)
+	}
+
+	return json.Marshal(messages[0])
+}
+
+// Notify calls method without waiting for it to complete.
+func (c *Client) Notify(method string, params interface{}) error {
+	return c.send(method, params)
+}
+
+func (c *Client) send(method string, params interface{}) error {
+	request := exchange.JsonRpcRequest{
+		JsonRpc: exchange.JsonRpcVersion,
+		Method:  method,
+	}
+
+	if params != nil {
+		packedParams, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		request.Params = packedParams
+	}
+
+	packed, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return c.write(packed)
+}
+
+func (c *Client) write(packed []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.socket.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.socket.WriteMessage(websocket.TextMessage, packed)
+}
+
+func (c *Client) read() {
+	for {
+		_, message, err := c.socket.ReadMessage()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		responses, ok := c.pendingFor(message)
+		if ok {
+			responses <- message
+		}
+	}
+}
+
+// pendingFor returns the channel of the call waiting for a response or batch
+// response. A batch is answered at once, any of its ids finds the call.
+func (c *Client) pendingFor(message []byte) (chan []byte, bool) {
+	responses, _, err := unpackMessages(message)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, response := range responses {
+		if pending, ok := c.pending[string(response["id"])]; ok {
+			return pending, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Client) fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		err = ErrClientClosed
+	}
+
+	c.err = err
+	close(c.closed)
+}
+
+func (c *Client) closeErr() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+// Close closes the connection. Pending calls fail with ErrClientClosed.
+func (c *Client) Close() error {
+	c.writeMutex.Lock()
+	c.socket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+	c.writeMutex.Unlock()
+
+	c.fail(ErrClientClosed)
+	return c.socket.Close()
+}
@@ -0,0 +1,371 @@
+package wstransport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/akaumov/go-service/exchange"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DefaultMaxMessageSize = 1 << 20
+	DefaultMaxInFlight    = 16
+	DefaultPingInterval   = 30 * time.Second
+
+	writeTimeout = 10 * time.Second
+)
+
+// SessionExtractor builds the session of a connection from its upgrade request.
+// An error rejects the connection with 401 Unauthorized, its message is only
+// sent for an *exchange.Error like the ones of exchange.Public.
+type SessionExtractor func(r *http.Request) (exchange.Session, error)
+
+// Server serves every WebSocket connection with an executor. Every text message
+// is a request, requests of a connection run concurrently.
+type Server struct {
+	execute        exchange.ExecuteFunc
+	extractSession SessionExtractor
+	upgrader       websocket.Upgrader
+	maxMessageSize int64
+	maxInFlight    int
+	pingInterval   time.Duration
+
+	mutex       sync.Mutex
+	connections map[*connection]bool
+	closed      bool
+	requests    sync.WaitGroup
+}
+
+type Option func(s *Server)
+
+func WithSessionExtractor(extractor SessionExtractor) Option {
+	return func(s *Server) {
+		s.extractSession = extractor
+	}
+}
+
+func WithMaxMessageSize(size int64) Option {
+	return func(s *Server) {
+		s.maxMessageSize = size
+	}
+}
+
+// WithMaxInFlight limits the requests of a connection run at the same time.
+// Reading of further messages waits for a free slot, the connection is kept alive
+// meanwhile.
+func WithMaxInFlight(limit int) Option {
+	return func(s *Server) {
+		s.maxInFlight = limit
+	}
+}
+
+// WithPingInterval sets how often connections are pinged. A connection that
+// doesn't answer within two intervals is closed.
+func WithPingInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.pingInterval = interval
+	}
+}
+
+// WithCheckOrigin replaces the default check, which only accepts requests from
+// the host of the server.
+func WithCheckOrigin(checkOrigin func(r *http.Request) bool) Option {
+	return func(s *Server) {
+		s.upgrader.CheckOrigin = checkOrigin
+	}
+}
+
+func NewServer(executor exchange.Executor, options ...Option) *Server {
+	return newServer(exchange.ExecutorFunc(executor), options)
+}
+
+// NewContextServer serves an executor generated with the context option. The
+// context of a request is canceled when its connection is closed.
+func NewContextServer(executor exchange.ContextExecutor, options ...Option) *Server {
+	return newServer(exchange.ContextExecutorFunc(executor), options)
+}
+
+func newServer(execute exchange.ExecuteFunc, options []Option) *Server {
+	s := &Server{
+		execute:        execute,
+		extractSession: anonymousSession,
+		maxMessageSize: DefaultMaxMessageSize,
+		maxInFlight:    DefaultMaxInFlight,
+		pingInterval:   DefaultPingInterval,
+		connections:    map[*connection]bool{},
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+func anonymousSession(r *http.Request) (exchange.Session, error) {
+	return exchange.StaticSession{}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	session, err := s.extractSession(r)
+	if err != nil {
+		message := "unauthorized"
+		if publicErr, ok := err.(*exchange.Error); ok {
+			message = publicErr.Message
+		}
+
+		http.Error(w, message, http.StatusUnauthorized)
+		return
+	}
+
+	s.mutex.Lock()
+	closed := s.closed
+	s.mutex.Unlock()
+
+	if closed {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	socket, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered the request
+		return
+	}
+
+	c := newConnection(s, socket, session)
+	if !s.track(c) {
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+		return
+	}
+	defer s.untrack(c)
+
+	c.serve()
+}
+
+func (s *Server) track(c *connection) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.connections[c] = true
+	return true
+}
+
+func (s *Server) untrack(c *connection) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.connections, c)
+}
+
+// Shutdown stops accepting connections and requests, closes every connection
+// and waits for running requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closed = true
+	connections := make([]*connection, 0, len(s.connections))
+	for c := range s.connections {
+		connections = append(connections, c)
+	}
+	s.mutex.Unlock()
+
+	for _, c := range connections {
+		c.stopReading()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.requests.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	for _, c := range connections {
+		c.close(websocket.CloseGoingAway, "server is shutting down")
+	}
+
+	return ctx.Err()
+}
+
+type connection struct {
+	server  *Server
+	socket  *websocket.Conn
+	session exchange.Session
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMutex sync.Mutex
+	closeOnce  sync.Once
+	inFlight   chan struct{}
+	requests   sync.WaitGroup
+
+	stateMutex sync.Mutex
+	stopping   bool
+}
+
+func newConnection(server *Server, socket *websocket.Conn, session exchange.Session) *connection {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	maxInFlight := server.maxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+
+	return &connection{
+		server:   server,
+		socket:   socket,
+		session:  session,
+		ctx:      ctx,
+		cancel:   cancel,
+		inFlight: make(chan struct{}, maxInFlight),
+	}
+}
+
+func (c *connection) serve() {
+	defer func() {
+		if c.isStopping() {
+			c.requests.Wait()
+			c.close(websocket.CloseGoingAway, "server is shutting down")
+			return
+		}
+
+		c.close(websocket.CloseNormalClosure, "")
+	}()
+
+	pongWait := 2 * c.server.pingInterval
+
+	c.socket.SetReadLimit(c.server.maxMessageSize)
+	c.extendReadDeadline(pongWait)
+	c.socket.SetPongHandler(func(string) error {
+		c.extendReadDeadline(pongWait)
+		return nil
+	})
+
+	go c.ping()
+
+	for {
+		messageType, message, err := c.socket.ReadMessage()
+		if err != nil || c.isStopping() {
+			return
+		}
+		c.extendReadDeadline(pongWait)
+
+		if messageType != websocket.TextMessage {
+			c.close(websocket.CloseUnsupportedData, "only text messages are accepted")
+			return
+		}
+
+		if !c.acquire(pongWait) {
+			return
+		}
+
+		c.server.requests.Add(1)
+		c.requests.Add(1)
+		go c.handle(message)
+	}
+}
+
+// acquire waits for a free request slot. Pongs are only read along with
+// messages, so the read deadline is extended while waiting, a dead peer is
+// still noticed by failing pings.
+func (c *connection) acquire(pongWait time.Duration) bool {
+	ticker := time.NewTicker(c.server.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case c.inFlight <- struct{}{}:
+			c.extendReadDeadline(pongWait)
+			return true
+		case <-ticker.C:
+			c.extendReadDeadline(pongWait)
+		case <-c.ctx.Done():
+			return false
+		}
+	}
+}
+
+func (c *connection) handle(message []byte) {
+	defer c.server.requests.Done()
+	defer c.requests.Done()
+	defer func() { <-c.inFlight }()
+
+	response, err := c.server.execute(c.ctx, c.session, message)
+	if err != nil || response == nil {
+		return
+	}
+
+	c.write(websocket.TextMessage, response)
+}
+
+func (c *connection) write(messageType int, data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.socket.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.socket.WriteMessage(messageType, data)
+}
+
+func (c *connection) ping() {
+	ticker := time.NewTicker(c.server.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := c.socket.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			if err != nil {
+				c.close(websocket.CloseGoingAway, "ping failed")
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// stopReading makes the connection stop taking new requests while running
+// requests can still write their responses.
+func (c *connection) stopReading() {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	c.stopping = true
+	c.socket.SetReadDeadline(time.Now())
+}
+
+func (c *connection) extendReadDeadline(wait time.Duration) {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if !c.stopping {
+		c.socket.SetReadDeadline(time.Now().Add(wait))
+	}
+}
+
+func (c *connection) isStopping() bool {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return c.stopping
+}
+
+func (c *connection) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.socket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+		c.socket.Close()
+	})
+}
@@ -0,0 +1,343 @@
+package wstransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akaumov/go-service/exchange"
+	"github.com/gorilla/websocket"
+)
+
+// testExecutor answers JSON-RPC 2.0 requests: "echo" returns its params after
+// delay, "block" signals started and waits for release.
+type testExecutor struct {
+	delay   time.Duration
+	started chan struct{}
+	release chan struct{}
+
+	mutex      sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (e *testExecutor) Execute(ctx context.Context, session exchange.Session, packedMessage *[]byte) (*[]byte, error) {
+	response := exchange.ExecuteJsonRpc(*packedMessage, 1, func(method string, params json.RawMessage) (interface{}, *exchange.Error) {
+		e.mutex.Lock()
+		e.running++
+		if e.running > e.maxRunning {
+			e.maxRunning = e.running
+		}
+		e.mutex.Unlock()
+
+		defer func() {
+			e.mutex.Lock()
+			e.running--
+			e.mutex.Unlock()
+		}()
+
+		switch method {
+		case "echo":
+			time.Sleep(e.delay)
+			return params, nil
+		case "block":
+			e.started <- struct{}{}
+			<-e.release
+			return "released", nil
+		}
+
+		return nil, exchange.NewMethodNotFoundError(method)
+	})
+
+	if response == nil {
+		return nil, nil
+	}
+
+	return &response, nil
+}
+
+func startServer(t *testing.T, executor *testExecutor, options ...Option) (*Server, string) {
+	server := NewContextServer(executor, options...)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	return server, "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+func dial(t *testing.T, url string) *Client {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	const (
+		calls       = 20
+		maxInFlight = 4
+	)
+
+	executor := &testExecutor{delay: 20 * time.Millisecond}
+	_, url := startServer(t, executor, WithMaxInFlight(maxInFlight))
+	client := dial(t, url)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var group sync.WaitGroup
+	errs := make(chan error, calls)
+
+	for index := 0; index < calls; index++ {
+		group.Add(1)
+
+		go func(index int) {
+			defer group.Done()
+
+			var result []int
+			err := client.Call(ctx, "echo", []int{index}, &result)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if len(result) != 1 || result[0] != index {
+				errs <- fmt.Errorf("call %v got %v", index, result)
+			}
+		}(index)
+	}
+
+	group.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	executor.mutex.Lock()
+	maxRunning := executor.maxRunning
+	executor.mutex.Unlock()
+
+	if maxRunning < 2 || maxRunning > maxInFlight {
+		t.Errorf("%v calls ran at a time, want 2 to %v", maxRunning, maxInFlight)
+	}
+}
+
+// TestBusyConnection checks that a connection waiting for a free request slot
+// longer than the pong wait isn't dropped.
+func TestBusyConnection(t *testing.T) {
+	const pingInterval = 20 * time.Millisecond
+
+	executor := &testExecutor{delay: 5 * pingInterval}
+	_, url := startServer(t, executor, WithMaxInFlight(1), WithPingInterval(pingInterval))
+	client := dial(t, url)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var group sync.WaitGroup
+	errs := make(chan error, 3)
+
+	for index := 0; index < 3; index++ {
+		group.Add(1)
+
+		go func(index int) {
+			defer group.Done()
+
+			var result []int
+			if err := client.Call(ctx, "echo", []int{index}, &result); err != nil {
+				errs <- err
+			}
+		}(index)
+	}
+
+	group.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	_, url := startServer(t, &testExecutor{})
+	client := dial(t, url)
+
+	err := client.Call(context.Background(), "unknown", nil, nil)
+
+	var callErr *exchange.Error
+	if !errors.As(err, &callErr) || callErr.Code != exchange.MethodNotFoundCode {
+		t.Errorf("error = %v, want code %v", err, exchange.MethodNotFoundCode)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		response string
+		isError  bool
+	}{
+		{
+			name:     "request",
+			request:  `{"jsonrpc":"2.0","id":"a","method":"echo","params":[1]}`,
+			response: `{"jsonrpc":"2.0","id":"a","result":[1]}`,
+		},
+		{
+			name:     "batch",
+			request:  `[{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]},{"jsonrpc":"2.0","method":"echo"},{"jsonrpc":"2.0","id":"b","method":"unknown"}]`,
+			response: `[{"jsonrpc":"2.0","id":1,"result":[1]},{"jsonrpc":"2.0","id":"b","error":{"code":-32601,"message":"no such method: unknown"}}]`,
+		},
+		{
+			name:     "same ids",
+			request:  `[{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]},{"jsonrpc":"2.0","id":1,"method":"echo","params":[2]}]`,
+			response: `[{"jsonrpc":"2.0","id":1,"result":[1]},{"jsonrpc":"2.0","id":1,"result":[2]}]`,
+		},
+		{name: "notification", request: `{"jsonrpc":"2.0","method":"echo"}`},
+		{name: "empty batch", request: `[]`, isError: true},
+		{name: "not an object", request: `[1]`, isError: true},
+	}
+
+	_, url := startServer(t, &testExecutor{})
+	client := dial(t, url)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			response, err := client.RoundTrip(ctx, []byte(test.request))
+			if test.isError {
+				if err == nil {
+					t.Fatalf("no error, want one")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if test.response == "" {
+				if response != nil {
+					t.Errorf("response = %s, want none", response)
+				}
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(response, &got); err != nil {
+				t.Fatalf("can't parse response %s: %v", response, err)
+			}
+			json.Unmarshal([]byte(test.response), &want)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("response = %s, want %s", response, test.response)
+			}
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	executor := &testExecutor{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	server, url := startServer(t, executor)
+	client := dial(t, url)
+
+	results := make(chan error, 1)
+	go func() {
+		var result string
+		err := client.Call(context.Background(), "block", nil, &result)
+		if err == nil && result != "released" {
+			err = fmt.Errorf("result = %q", result)
+		}
+		results <- err
+	}()
+
+	<-executor.started
+
+	shutdownErrs := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shutdownErrs <- server.Shutdown(ctx)
+	}()
+
+	// new connections are refused while running requests finish
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, response, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil && response != nil && response.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("connections are still accepted during shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(executor.release)
+
+	if err := <-results; err != nil {
+		t.Errorf("running call failed: %v", err)
+	}
+
+	if err := <-shutdownErrs; err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Call(ctx, "echo", nil, nil); err == nil {
+		t.Errorf("call after shutdown succeeded")
+	}
+}
+
+func TestSessionExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		message string
+	}{
+		{name: "hidden error", err: errors.New("token signature of user 42 is invalid"), message: "unauthorized"},
+		{name: "public error", err: exchange.Public(errors.New("token expired")), message: "token expired"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, url := startServer(t, &testExecutor{}, WithSessionExtractor(func(r *http.Request) (exchange.Session, error) {
+				return nil, test.err
+			}))
+
+			_, response, err := websocket.DefaultDialer.Dial(url, nil)
+			if err == nil || response == nil {
+				t.Fatalf("connection was accepted")
+			}
+			defer response.Body.Close()
+
+			body, _ := ioutil.ReadAll(response.Body)
+			if response.StatusCode != http.StatusUnauthorized || strings.TrimSpace(string(body)) != test.message {
+				t.Errorf("response = %v %q, want %v %q", response.StatusCode, body, http.StatusUnauthorized, test.message)
+			}
+		})
+	}
+}